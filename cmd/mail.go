@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"time"
+	utils "vk-rest/pkg"
+	"vk-rest/pkg/models"
+	"vk-rest/service/usecase/worker"
+)
+
+const testMailTimeout = 30 * time.Second
+
+// sendTestMail checks the SMTP settings by sending a mail to the address
+// given as the only argument.
+func sendTestMail(log *logrus.Logger, args []string) error {
+	if len(args) != 1 || !utils.ValidEmail(args[0]) {
+		return errors.New("usage: app send-test-mail <email>")
+	}
+
+	mailCfg, err := worker.GetMailConfig()
+	if err != nil {
+		return fmt.Errorf("create mail config error: %s", err.Error())
+	}
+
+	notifier := worker.GetNotifiers(mailCfg, &models.NotifierConfig{})[models.ChannelEmail]
+
+	ctx, cancel := context.WithTimeout(context.Background(), testMailTimeout)
+	defer cancel()
+
+	err = notifier.Notify(ctx, &models.Mail{
+		To:      args[0],
+		Subject: "Test mail",
+		Body:    "This is a test mail from the birthday service.",
+	})
+	if err != nil {
+		return fmt.Errorf("send mail error: %s", err.Error())
+	}
+
+	log.Infof("Test mail sent to %s via %s:%d", args[0], mailCfg.AddrHost, mailCfg.Port)
+	return nil
+}
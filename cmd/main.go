@@ -1,62 +1,58 @@
 package main
 
 import (
+	"fmt"
 	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
 	"os"
-	"vk-rest/configs"
+	_ "time/tzdata"
 	"vk-rest/configs/logger"
-	"vk-rest/service/delivery/http"
-	"vk-rest/service/usecase/core"
-	"vk-rest/service/usecase/worker"
 )
 
+const usage = `usage: app <command> [arguments]
+
+commands:
+  serve                    run the HTTP API
+  worker                   run the birthday scheduler, the manual runs and the
+                           outbox dispatcher
+  migrate up|down [n]|status
+                           apply, revert or list the schema migrations
+  send-test-mail <email>   send a test e-mail with the configured SMTP server`
+
+// commands maps every subcommand to its entry point. Each one builds only
+// the repositories it uses, so the API and the worker can be deployed and
+// scaled separately: serve needs neither the SMTP settings nor the worker
+// lease, it hands manual runs to the worker through the database.
+var commands = map[string]func(log *logrus.Logger, args []string) error{
+	"serve":          serve,
+	"worker":         runWorker,
+	"migrate":        migrate,
+	"send-test-mail": sendTestMail,
+}
+
 func main() {
 	log := logger.GetLogger()
-	err := godotenv.Load()
-	if err != nil {
-		log.Errorf("load .env error: %s", err.Error())
-		return
-	}
 
-	psxCfg, err := configs.GetPsxConfig()
-	if err != nil {
-		log.Error("Create profile config error: ", err)
-		return
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
 	}
 
-	redisCfg, err := configs.GetRedisConfig()
-	if err != nil {
-		log.Error("Create redis config error: ", err)
-		return
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n%s\n", os.Args[1], usage)
+		os.Exit(2)
 	}
 
-	core, err := usecase.GetCore(psxCfg, redisCfg, log)
-	if err != nil {
-		log.Error("Create core error: ", err)
-		return
-	}
-
-	api := delivery.GetApi(core, log)
-
-	w, err := worker.GetWorker(psxCfg, log)
-	if err != nil {
-		log.Error("Create worker error: ", err.Error())
-		return
-	}
-
-	err = w.StartWorker()
+	err := godotenv.Load()
 	if err != nil {
-		log.Error("Start worker error: ", err.Error())
-		return
+		log.Errorf("load .env error: %s", err.Error())
+		os.Exit(1)
 	}
 
-	port := os.Getenv("APP_PORT")
-	log.Info("Server running on port: ", port)
-
-	err = api.ListenAndServe(port)
+	err = command(log, os.Args[2:])
 	if err != nil {
-		log.Error("ListenAndServe error: ", err)
-		return
+		log.Errorf("%s error: %s", os.Args[1], err.Error())
+		os.Exit(1)
 	}
-
 }
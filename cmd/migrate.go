@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+	"vk-rest/configs"
+	"vk-rest/service/repository/psx"
+	"vk-rest/service/repository/schema"
+)
+
+const migrateUsage = "usage: app migrate up | down [steps] | status"
+
+// migrate applies, reverts or lists the embedded schema migrations.
+func migrate(log *logrus.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	steps := 1
+	if args[0] == "down" && len(args) == 2 {
+		var err error
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			return errors.New(migrateUsage)
+		}
+	} else if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	psxCfg, err := configs.GetPsxConfig()
+	if err != nil {
+		return fmt.Errorf("create profile config error: %s", err.Error())
+	}
+
+	db, err := psx.GetPsxDb(psxCfg, log)
+	if err != nil {
+		return fmt.Errorf("connect to postgres error: %s", err.Error())
+	}
+	defer db.Close()
+
+	repo, err := schema.GetSchemaRepo(db, log)
+	if err != nil {
+		return fmt.Errorf("create schema repo error: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := repo.Up(ctx)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Applied %d migrations", len(applied))
+	case "down":
+		reverted, err := repo.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Reverted %d migrations", len(reverted))
+	case "status":
+		status, err := repo.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range status {
+			applied := "pending"
+			if migration.AppliedAt != nil {
+				applied = migration.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", migration.Version, migration.Name, applied)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"vk-rest/configs"
+	"vk-rest/service/delivery/http"
+	"vk-rest/service/repository/psx"
+	"vk-rest/service/usecase/core"
+)
+
+// serve runs the HTTP API. Manual runs of the birthday job are only recorded
+// here and done by the worker command.
+func serve(log *logrus.Logger, _ []string) error {
+	psxCfg, err := configs.GetPsxConfig()
+	if err != nil {
+		return fmt.Errorf("create profile config error: %s", err.Error())
+	}
+
+	db, err := psx.GetPsxDb(psxCfg, log)
+	if err != nil {
+		return fmt.Errorf("connect to postgres error: %s", err.Error())
+	}
+	defer db.Close()
+
+	redisCfg, err := configs.GetRedisConfig()
+	if err != nil {
+		return fmt.Errorf("create redis config error: %s", err.Error())
+	}
+
+	sessionCfg, err := configs.GetSessionConfig()
+	if err != nil {
+		return fmt.Errorf("create session config error: %s", err.Error())
+	}
+
+	birthdayCfg, err := configs.GetBirthdayConfig()
+	if err != nil {
+		return fmt.Errorf("create birthday config error: %s", err.Error())
+	}
+
+	core, err := usecase.GetCore(db, redisCfg, sessionCfg, birthdayCfg, log)
+	if err != nil {
+		return fmt.Errorf("create core error: %s", err.Error())
+	}
+
+	api := delivery.GetApi(core, log)
+
+	port := os.Getenv("APP_PORT")
+	log.Info("Server running on port: ", port)
+
+	return api.ListenAndServe(port)
+}
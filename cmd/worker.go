@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"syscall"
+	"vk-rest/configs"
+	"vk-rest/service/repository/psx"
+	"vk-rest/service/usecase/worker"
+)
+
+// runWorker runs the birthday job and the outbox dispatcher until the
+// process is stopped.
+func runWorker(log *logrus.Logger, _ []string) error {
+	psxCfg, err := configs.GetPsxConfig()
+	if err != nil {
+		return fmt.Errorf("create profile config error: %s", err.Error())
+	}
+
+	db, err := psx.GetPsxDb(psxCfg, log)
+	if err != nil {
+		return fmt.Errorf("connect to postgres error: %s", err.Error())
+	}
+	defer db.Close()
+
+	redisCfg, err := configs.GetRedisConfig()
+	if err != nil {
+		return fmt.Errorf("create redis config error: %s", err.Error())
+	}
+
+	birthdayCfg, err := configs.GetBirthdayConfig()
+	if err != nil {
+		return fmt.Errorf("create birthday config error: %s", err.Error())
+	}
+
+	w, err := worker.GetWorker(db, redisCfg, birthdayCfg, log)
+	if err != nil {
+		return fmt.Errorf("create worker error: %s", err.Error())
+	}
+
+	err = w.StartWorker()
+	if err != nil {
+		return fmt.Errorf("start worker error: %s", err.Error())
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+
+	log.Info("Worker stopped by ", sig)
+	return nil
+}
@@ -1,7 +1,10 @@
 package configs
 
 import (
+	"fmt"
 	"github.com/spf13/viper"
+	"time"
+	"vk-rest/pkg/birthday"
 )
 
 type DbPsxConfig struct {
@@ -22,6 +25,19 @@ type DbRedisCfg struct {
 	Timer    int    `yaml:"timer"`
 }
 
+type SessionCfg struct {
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	MaxLifetime time.Duration `yaml:"max_lifetime"`
+}
+
+type BirthdayCfg struct {
+	LeapDayPolicy birthday.LeapDayPolicy `yaml:"leap_day_policy"`
+	CatchUpDays   int                    `yaml:"catch_up_days"`
+}
+
+// maxCatchUpDays limits how far back missed days are replayed.
+const maxCatchUpDays = 30
+
 func GetPsxConfig() (*DbPsxConfig, error) {
 	v := viper.GetViper()
 	v.AutomaticEnv()
@@ -53,3 +69,43 @@ func GetRedisConfig() (*DbRedisCfg, error) {
 
 	return cfg, nil
 }
+
+func GetSessionConfig() (*SessionCfg, error) {
+	v := viper.GetViper()
+	v.AutomaticEnv()
+	v.SetDefault("SESSION_IDLE_TIMEOUT", 24*time.Hour)
+	v.SetDefault("SESSION_MAX_LIFETIME", 7*24*time.Hour)
+
+	cfg := &SessionCfg{
+		IdleTimeout: v.GetDuration("SESSION_IDLE_TIMEOUT"),
+		MaxLifetime: v.GetDuration("SESSION_MAX_LIFETIME"),
+	}
+
+	if cfg.IdleTimeout <= 0 || cfg.MaxLifetime < cfg.IdleTimeout {
+		return nil, fmt.Errorf("bad session timeouts: idle %s, max %s", cfg.IdleTimeout, cfg.MaxLifetime)
+	}
+
+	return cfg, nil
+}
+
+func GetBirthdayConfig() (*BirthdayCfg, error) {
+	v := viper.GetViper()
+	v.AutomaticEnv()
+	v.SetDefault("LEAP_DAY_POLICY", string(birthday.LeapDayFeb28))
+	v.SetDefault("CATCHUP_DAYS", 3)
+
+	cfg := &BirthdayCfg{
+		LeapDayPolicy: birthday.LeapDayPolicy(v.GetString("LEAP_DAY_POLICY")),
+		CatchUpDays:   v.GetInt("CATCHUP_DAYS"),
+	}
+
+	if !birthday.ValidPolicy(cfg.LeapDayPolicy) {
+		return nil, fmt.Errorf("bad leap day policy: %s", cfg.LeapDayPolicy)
+	}
+
+	if cfg.CatchUpDays < 0 || cfg.CatchUpDays > maxCatchUpDays {
+		return nil, fmt.Errorf("bad catch up days: %d", cfg.CatchUpDays)
+	}
+
+	return cfg, nil
+}
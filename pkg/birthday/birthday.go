@@ -0,0 +1,98 @@
+package birthday
+
+import (
+	"time"
+)
+
+// LeapDayPolicy says when people born on February 29 celebrate in common
+// years.
+type LeapDayPolicy string
+
+const (
+	LeapDayFeb28 LeapDayPolicy = "feb28"
+	LeapDayMar1  LeapDayPolicy = "mar1"
+	LeapDaySkip  LeapDayPolicy = "skip"
+)
+
+// leapYearsAhead is enough years to always reach the next leap year.
+const leapYearsAhead = 8
+
+func ValidPolicy(policy LeapDayPolicy) bool {
+	return policy == LeapDayFeb28 || policy == LeapDayMar1 || policy == LeapDaySkip
+}
+
+// Date returns the midnight UTC of the calendar day of t, so days can be
+// counted without time zone and daylight saving effects.
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func IsLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+func isLeapDay(birthday time.Time) bool {
+	return birthday.Month() == time.February && birthday.Day() == 29
+}
+
+// InYear returns the day the birthday is celebrated in year. ok is false
+// when a February 29 birthday is skipped in a common year.
+func InYear(birthday time.Time, year int, policy LeapDayPolicy) (day time.Time, ok bool) {
+	if isLeapDay(birthday) && !IsLeapYear(year) {
+		switch policy {
+		case LeapDayFeb28:
+			return time.Date(year, time.February, 28, 0, 0, 0, 0, time.UTC), true
+		case LeapDayMar1:
+			return time.Date(year, time.March, 1, 0, 0, 0, 0, time.UTC), true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	return time.Date(year, birthday.Month(), birthday.Day(), 0, 0, 0, 0, time.UTC), true
+}
+
+// Next returns the first day on or after today the birthday is celebrated.
+func Next(birthday, today time.Time, policy LeapDayPolicy) time.Time {
+	today = Date(today)
+
+	for year := today.Year(); year <= today.Year()+leapYearsAhead; year++ {
+		day, ok := InYear(birthday, year, policy)
+		if ok && !day.Before(today) {
+			return day
+		}
+	}
+
+	return time.Time{}
+}
+
+// Is reports whether the birthday is celebrated on day.
+func Is(birthday, day time.Time, policy LeapDayPolicy) bool {
+	celebrated, ok := InYear(birthday, day.Year(), policy)
+	return ok && celebrated.Equal(Date(day))
+}
+
+// MonthDays returns the MM-DD birth dates celebrated on day: the day itself
+// and February 29 when the policy moves it to day.
+func MonthDays(day time.Time, policy LeapDayPolicy) []string {
+	days := []string{day.Format("01-02")}
+
+	leapDay := time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)
+	if !IsLeapYear(day.Year()) && Is(leapDay, day, policy) {
+		days = append(days, leapDay.Format("01-02"))
+	}
+
+	return days
+}
+
+// Age returns how old someone born on birthday turns on the birthday in the
+// year of on.
+func Age(birthday, on time.Time) int {
+	return on.Year() - birthday.Year()
+}
+
+// DaysUntil returns the number of days from today to day, negative when day
+// has passed.
+func DaysUntil(today, day time.Time) int {
+	return int(Date(day).Sub(Date(today)).Hours() / 24)
+}
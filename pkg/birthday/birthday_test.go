@@ -0,0 +1,135 @@
+package birthday
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+var leapBorn = date(1996, time.February, 29)
+
+func TestIsLeapYear(t *testing.T) {
+	for year, leap := range map[int]bool{2000: true, 2023: false, 2024: true, 2100: false} {
+		if IsLeapYear(year) != leap {
+			t.Errorf("IsLeapYear(%d) = %t, want %t", year, !leap, leap)
+		}
+	}
+}
+
+func TestInYear(t *testing.T) {
+	tests := []struct {
+		policy LeapDayPolicy
+		year   int
+		want   time.Time
+		ok     bool
+	}{
+		{LeapDayFeb28, 2023, date(2023, time.February, 28), true},
+		{LeapDayFeb28, 2024, date(2024, time.February, 29), true},
+		{LeapDayFeb28, 2100, date(2100, time.February, 28), true},
+		{LeapDayFeb28, 2000, date(2000, time.February, 29), true},
+		{LeapDayMar1, 2023, date(2023, time.March, 1), true},
+		{LeapDayMar1, 2024, date(2024, time.February, 29), true},
+		{LeapDayMar1, 2100, date(2100, time.March, 1), true},
+		{LeapDayMar1, 2000, date(2000, time.February, 29), true},
+		{LeapDaySkip, 2023, time.Time{}, false},
+		{LeapDaySkip, 2024, date(2024, time.February, 29), true},
+		{LeapDaySkip, 2100, time.Time{}, false},
+		{LeapDaySkip, 2000, date(2000, time.February, 29), true},
+	}
+
+	for _, tt := range tests {
+		got, ok := InYear(leapBorn, tt.year, tt.policy)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("InYear(%s, %d) = %s, %t, want %s, %t", tt.policy, tt.year, got.Format(time.DateOnly), ok, tt.want.Format(time.DateOnly), tt.ok)
+		}
+	}
+}
+
+func TestInYearCommonBirthday(t *testing.T) {
+	born := date(1990, time.March, 1)
+
+	for _, policy := range []LeapDayPolicy{LeapDayFeb28, LeapDayMar1, LeapDaySkip} {
+		for _, year := range []int{2000, 2023, 2024, 2100} {
+			got, ok := InYear(born, year, policy)
+			if !ok || !got.Equal(date(year, time.March, 1)) {
+				t.Errorf("InYear(%s, %d) = %s, %t, want March 1", policy, year, got.Format(time.DateOnly), ok)
+			}
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		policy LeapDayPolicy
+		today  time.Time
+		want   time.Time
+	}{
+		{LeapDayFeb28, date(2023, time.January, 10), date(2023, time.February, 28)},
+		{LeapDayFeb28, date(2023, time.February, 28), date(2023, time.February, 28)},
+		{LeapDayFeb28, date(2023, time.March, 1), date(2024, time.February, 29)},
+		{LeapDayFeb28, date(2024, time.February, 29), date(2024, time.February, 29)},
+		{LeapDayFeb28, date(2100, time.January, 10), date(2100, time.February, 28)},
+		{LeapDayFeb28, date(2000, time.January, 10), date(2000, time.February, 29)},
+		{LeapDayMar1, date(2023, time.February, 28), date(2023, time.March, 1)},
+		{LeapDayMar1, date(2023, time.March, 2), date(2024, time.February, 29)},
+		{LeapDayMar1, date(2024, time.January, 10), date(2024, time.February, 29)},
+		{LeapDayMar1, date(2100, time.February, 28), date(2100, time.March, 1)},
+		{LeapDayMar1, date(2000, time.March, 1), date(2001, time.March, 1)},
+		{LeapDaySkip, date(2023, time.January, 10), date(2024, time.February, 29)},
+		{LeapDaySkip, date(2024, time.March, 1), date(2028, time.February, 29)},
+		{LeapDaySkip, date(2100, time.January, 10), date(2104, time.February, 29)},
+		{LeapDaySkip, date(2000, time.February, 29), date(2000, time.February, 29)},
+	}
+
+	for _, tt := range tests {
+		got := Next(leapBorn, tt.today, tt.policy)
+		if !got.Equal(tt.want) {
+			t.Errorf("Next(%s, %s) = %s, want %s", tt.policy, tt.today.Format(time.DateOnly), got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestNextIgnoresTimeOfDay(t *testing.T) {
+	today := time.Date(2023, time.February, 28, 23, 30, 0, 0, time.FixedZone("UTC+9", 9*60*60))
+
+	got := Next(leapBorn, today, LeapDayFeb28)
+	if !got.Equal(date(2023, time.February, 28)) {
+		t.Errorf("Next = %s, want 2023-02-28", got.Format(time.DateOnly))
+	}
+}
+
+func TestMonthDays(t *testing.T) {
+	tests := []struct {
+		policy LeapDayPolicy
+		day    time.Time
+		want   []string
+	}{
+		{LeapDayFeb28, date(2023, time.February, 28), []string{"02-28", "02-29"}},
+		{LeapDayFeb28, date(2023, time.March, 1), []string{"03-01"}},
+		{LeapDayFeb28, date(2024, time.February, 28), []string{"02-28"}},
+		{LeapDayFeb28, date(2024, time.February, 29), []string{"02-29"}},
+		{LeapDayFeb28, date(2100, time.February, 28), []string{"02-28", "02-29"}},
+		{LeapDayFeb28, date(2000, time.February, 28), []string{"02-28"}},
+		{LeapDayMar1, date(2023, time.February, 28), []string{"02-28"}},
+		{LeapDayMar1, date(2023, time.March, 1), []string{"03-01", "02-29"}},
+		{LeapDayMar1, date(2024, time.March, 1), []string{"03-01"}},
+		{LeapDayMar1, date(2100, time.March, 1), []string{"03-01", "02-29"}},
+		{LeapDayMar1, date(2000, time.March, 1), []string{"03-01"}},
+		{LeapDaySkip, date(2023, time.February, 28), []string{"02-28"}},
+		{LeapDaySkip, date(2023, time.March, 1), []string{"03-01"}},
+		{LeapDaySkip, date(2024, time.February, 29), []string{"02-29"}},
+		{LeapDaySkip, date(2100, time.February, 28), []string{"02-28"}},
+		{LeapDaySkip, date(2000, time.February, 29), []string{"02-29"}},
+	}
+
+	for _, tt := range tests {
+		got := MonthDays(tt.day, tt.policy)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MonthDays(%s, %s) = %v, want %v", tt.day.Format(time.DateOnly), tt.policy, got, tt.want)
+		}
+	}
+}
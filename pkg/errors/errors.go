@@ -3,12 +3,42 @@ package errs
 import "errors"
 
 var ErrNotFound = errors.New("not found")
+var ErrDuplicate = errors.New("already exists")
+var ErrTemplate = errors.New("bad template")
+var ErrHidden = errors.New("hidden")
+var ErrSubscriptionRefused = errors.New("subscription refused")
+var ErrSchedule = errors.New("bad schedule")
+var ErrWorkerBusy = errors.New("worker is busy")
 var ErrDuplicateSub = errors.New("ERROR: duplicate key value violates unique constraint \"subscriber_pkey\" (SQLSTATE 23505)")
 
 var ErrMethodNotAllowed = "Method not found"
 var ErrInternalServer = "Internal server error"
 var ErrBadRequest = "Bad request"
 var ErrUnauthorized = "Unauthorized"
+var ErrForbidden = "Forbidden"
 var ErrNotFoundString = "Not found"
 var ErrNoBirthEmailLogin = "Not have birthday, email, password or login"
 var ErrAlreadyExists = "Already exists"
+var ErrUnknownTimezone = "Unknown timezone"
+var ErrBadChannel = "Unknown channel or bad address"
+var ErrUnknownLocale = "Unknown locale"
+var ErrBadTemplate = "Bad template"
+var ErrBadCard = "Card must be a png, jpeg or gif image up to 1 MB"
+var ErrUnknownRole = "Unknown role"
+var ErrBadEmail = "Bad email"
+var ErrBadBirthday = "Birthday must be a YYYY-MM-DD date not in the future"
+var ErrBadFilter = "Bad filter or sort"
+var ErrBadCursor = "Bad cursor"
+var ErrBadDays = "Days must be from 0 to 366"
+var ErrBadMonth = "Month must be YYYY-MM"
+var ErrMissingImportField = "Login, email and birthday are required"
+var ErrRepeatedLogin = "Login repeats an earlier row"
+var ErrBadImport = "Send text/csv or application/x-ndjson"
+var ErrBadToken = "Unknown or expired token"
+var ErrBadExportFormat = "Format must be csv, json or xlsx"
+var ErrBadTeamName = "Team name must not be empty"
+var ErrUnknownDeliveryMode = "Delivery mode must be immediate, daily or weekly"
+var ErrUnknownVisibility = "Birthday visibility must be full, no_year or hidden"
+var ErrRefusedSubscription = "Employee does not accept subscriptions"
+var ErrBadReminders = "Reminders must be up to 5 days from 0 to 60"
+var ErrBadDate = "Date must be YYYY-MM-DD"
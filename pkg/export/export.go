@@ -0,0 +1,126 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"vk-rest/pkg/xlsx"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+	FormatXLSX = "xlsx"
+)
+
+// Writer writes a table row by row. The first row passed to NewWriter names
+// the columns.
+type Writer interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSON || format == FormatXLSX
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatXLSX:
+		return xlsx.ContentType
+	}
+
+	return "text/csv; charset=utf-8"
+}
+
+// NewWriter returns a writer of format that streams to w.
+func NewWriter(format string, w io.Writer, name string, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		cw := &csvWriter{w: csv.NewWriter(w)}
+		err := cw.w.Write(columns)
+		if err != nil {
+			return nil, err
+		}
+		return cw, nil
+	case FormatJSON:
+		_, err := io.WriteString(w, "[")
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return &jsonWriter{w: w, enc: enc, columns: columns}, nil
+	case FormatXLSX:
+		xw, err := xlsx.NewWriter(w, name)
+		if err != nil {
+			return nil, err
+		}
+
+		header := make([]any, 0, len(columns))
+		for _, column := range columns {
+			header = append(header, column)
+		}
+
+		err = xw.WriteRow(header)
+		if err != nil {
+			return nil, err
+		}
+		return xw, nil
+	}
+
+	return nil, fmt.Errorf("unknown export format %s", format)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) WriteRow(values []any) error {
+	record := make([]string, 0, len(values))
+	for _, value := range values {
+		record = append(record, fmt.Sprint(value))
+	}
+
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter writes an array of objects keyed by the column names.
+type jsonWriter struct {
+	w       io.Writer
+	enc     *json.Encoder
+	columns []string
+	rows    int
+}
+
+func (j *jsonWriter) WriteRow(values []any) error {
+	if j.rows > 0 {
+		_, err := io.WriteString(j.w, ",")
+		if err != nil {
+			return err
+		}
+	}
+	j.rows++
+
+	object := make(map[string]any, len(values))
+	for i, value := range values {
+		if i < len(j.columns) {
+			object[j.columns[i]] = value
+		}
+	}
+
+	return j.enc.Encode(object)
+}
+
+func (j *jsonWriter) Close() error {
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
@@ -0,0 +1,88 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"vk-rest/pkg/birthday"
+)
+
+const ContentType = "text/calendar; charset=utf-8"
+
+// lineLimit is the longest content line in octets allowed by RFC 5545.
+const lineLimit = 75
+
+// Event is an all-day event that repeats by Rule.
+type Event struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	Rule    string
+	Stamp   time.Time
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// YearlyRule returns the RRULE of a birthday. February 29 birthdays follow
+// the leap day policy: the last day of February, the 60th day of the year,
+// which is March 1 in common years, or only leap years.
+func YearlyRule(born time.Time, policy birthday.LeapDayPolicy) string {
+	if born.Month() == time.February && born.Day() == 29 {
+		switch policy {
+		case birthday.LeapDayFeb28:
+			return "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"
+		case birthday.LeapDayMar1:
+			return "FREQ=YEARLY;BYYEARDAY=60"
+		}
+	}
+
+	return "FREQ=YEARLY"
+}
+
+// Calendar renders the events as a VCALENDAR object.
+func Calendar(name string, events []*Event) []byte {
+	var buf bytes.Buffer
+
+	writeLine(&buf, "BEGIN:VCALENDAR")
+	writeLine(&buf, "VERSION:2.0")
+	writeLine(&buf, "PRODID:-//vk-rest//birthdays//RU")
+	writeLine(&buf, "CALSCALE:GREGORIAN")
+	writeLine(&buf, "METHOD:PUBLISH")
+	writeLine(&buf, "X-WR-CALNAME:"+textEscaper.Replace(name))
+
+	for _, event := range events {
+		writeLine(&buf, "BEGIN:VEVENT")
+		writeLine(&buf, "UID:"+event.UID)
+		writeLine(&buf, "DTSTAMP:"+event.Stamp.UTC().Format("20060102T150405Z"))
+		writeLine(&buf, "DTSTART;VALUE=DATE:"+event.Start.Format("20060102"))
+		writeLine(&buf, "RRULE:"+event.Rule)
+		writeLine(&buf, "SUMMARY:"+textEscaper.Replace(event.Summary))
+		writeLine(&buf, "TRANSP:TRANSPARENT")
+		writeLine(&buf, "END:VEVENT")
+	}
+
+	writeLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// writeLine writes a content line folded into lines of at most lineLimit
+// octets without splitting UTF-8 characters.
+func writeLine(buf *bytes.Buffer, line string) {
+	limit := lineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		// the leading space of a continuation line counts towards its limit
+		limit = lineLimit - 1
+	}
+
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
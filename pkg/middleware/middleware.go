@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	errs "vk-rest/pkg/errors"
 	"vk-rest/pkg/models"
 	httpResponse "vk-rest/pkg/response"
 )
@@ -12,9 +13,11 @@ import (
 type contextKey string
 
 const UserIDKey contextKey = "userId"
+const RoleKey contextKey = "role"
 
 type IMiddleware interface {
 	GetUserId(ctx context.Context, sid string) (uint64, error)
+	GetUserRole(ctx context.Context, userId uint64) (string, error)
 }
 
 type Middleware struct {
@@ -50,6 +53,31 @@ func (m *Middleware) AuthCheck(next http.Handler) http.Handler {
 	})
 }
 
+// PermissionCheck lets the request through only if the role of the user
+// grants perm. It must be wrapped by AuthCheck.
+func (m *Middleware) PermissionCheck(next http.Handler, perm models.Permission) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(UserIDKey).(uint64)
+
+		role, err := m.Core.GetUserRole(r.Context(), userId)
+		if err != nil {
+			m.Lg.Error("permission check error: ", err.Error())
+			response := models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}
+			httpResponse.SendResponse(w, r, &response, m.Lg)
+			return
+		}
+
+		if !models.HasPermission(role, perm) {
+			response := models.Response{Status: http.StatusForbidden, Body: models.ErrorResponse{Error: errs.ErrForbidden}}
+			httpResponse.SendResponse(w, r, &response, m.Lg)
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), RoleKey, role))
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (m *Middleware) MethodCheck(next http.Handler, method string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != method {
@@ -60,3 +88,16 @@ func (m *Middleware) MethodCheck(next http.Handler, method string) http.Handler
 		next.ServeHTTP(w, r)
 	})
 }
+
+// MethodsCheck serves the request with the handler registered for its method.
+func (m *Middleware) MethodsCheck(handlers map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next, ok := handlers[r.Method]
+		if !ok {
+			response := models.Response{Status: http.StatusMethodNotAllowed, Body: models.ErrorResponse{Error: "Method not allowed"}}
+			httpResponse.SendResponse(w, r, &response, m.Lg)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
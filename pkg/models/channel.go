@@ -0,0 +1,21 @@
+package models
+
+const (
+	ChannelEmail    = "email"
+	ChannelWebhook  = "webhook"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+)
+
+// Channel is a delivery channel chosen by an employee. Address is the e-mail
+// for ChannelEmail, the URL for webhook channels and the chat id for
+// ChannelTelegram.
+type Channel struct {
+	Channel string `json:"channel"`
+	Address string `json:"address"`
+}
+
+type NotifierConfig struct {
+	TelegramToken  string
+	TelegramApiUrl string
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// ImportRow is one employee of a bulk import. Row is its 1-based position
+// among the data rows of the file.
+type ImportRow struct {
+	Row        int    `json:"-"`
+	Login      string `json:"login"`
+	Email      string `json:"email"`
+	Birthday   string `json:"birthday"`
+	Department string `json:"department"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Login string `json:"login,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportedEmployee carries the token the employee sets a password with,
+// since imported profiles have none.
+type ImportedEmployee struct {
+	Row            int       `json:"row"`
+	Id             uint64    `json:"id"`
+	Login          string    `json:"login"`
+	PasswordToken  string    `json:"password_token"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+}
+
+type ImportResult struct {
+	DryRun   bool                `json:"dry_run"`
+	Total    int                 `json:"total"`
+	Errors   []*ImportRowError   `json:"errors"`
+	Imported []*ImportedEmployee `json:"imported"`
+}
+
+type PasswordSetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
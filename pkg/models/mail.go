@@ -4,6 +4,8 @@ type Mail struct {
 	To      string
 	Subject string
 	Body    string
+	HTML    string
+	Card    *Card
 }
 
 type MailConfigServer struct {
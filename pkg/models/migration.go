@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Migration is one versioned step of the database schema. AppliedAt is nil
+// while the migration is pending.
+type Migration struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Up        string     `json:"-"`
+	Down      string     `json:"-"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
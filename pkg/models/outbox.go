@@ -0,0 +1,48 @@
+package models
+
+import "strconv"
+
+const (
+	OutboxKindGreeting = "greeting"
+	OutboxKindNotify   = "notify"
+	OutboxKindReminder = "reminder"
+	OutboxKindDigest   = "digest"
+)
+
+// ReminderKind is the outbox kind of the reminder sent days before a
+// birthday. Each offset has its own kind, so each is sent once a year.
+func ReminderKind(days int) string {
+	return OutboxKindReminder + "-" + strconv.Itoa(days)
+}
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+// DigestKind is the outbox kind of the digest in mode for period, so each
+// digest is sent once. A digest is stored with the recipient as BirthdayId.
+func DigestKind(mode, period string) string {
+	return OutboxKindDigest + "-" + mode + "-" + period
+}
+
+// OutboxMessage is a message stored before delivery. RecipientId, BirthdayId,
+// Year, Kind and Channel identify it, so enqueuing it twice is a no-op.
+type OutboxMessage struct {
+	Id          uint64
+	RecipientId uint64
+	BirthdayId  uint64
+	Year        int
+	Kind        string
+	Channel     string
+	To          string
+	Subject     string
+	Body        string
+	HTML        string
+	CardId      uint64
+	Attempts    int
+	// Fence is the fencing token of the worker leader that queues the
+	// message. It is checked on insert and not stored.
+	Fence int64
+}
@@ -1,8 +1,126 @@
 package models
 
+import "time"
+
 type UserItem struct {
-	Id       uint64 `json:"id"`
-	Login    string `json:"login"`
-	Email    string `json:"email"`
-	Birthday string `json:"birthday"`
+	Id         uint64 `json:"id"`
+	Login      string `json:"login"`
+	Email      string `json:"email"`
+	Birthday   string `json:"birthday"`
+	Timezone   string `json:"timezone"`
+	Locale     string `json:"locale"`
+	Department string `json:"department"`
+	Role       string `json:"role,omitempty"`
+	Visibility string `json:"birthday_visibility,omitempty"`
+}
+
+// Visibilities say how much of the birthday colleagues see.
+const (
+	VisibilityFull   = "full"
+	VisibilityNoYear = "no_year"
+	VisibilityHidden = "hidden"
+)
+
+var Visibilities = []string{VisibilityFull, VisibilityNoYear, VisibilityHidden}
+
+// HideBirthday removes from Birthday what the employee does not show to
+// colleagues: without the year it becomes "--MM-DD" as in ISO 8601, a
+// hidden birthday is emptied.
+func (u *UserItem) HideBirthday() {
+	switch u.Visibility {
+	case VisibilityNoYear:
+		if len(u.Birthday) >= len("2006-01-02") {
+			u.Birthday = "--" + u.Birthday[len("2006-"):len("2006-01-02")]
+		}
+	case VisibilityHidden:
+		u.Birthday = ""
+	}
+}
+
+// DeliveryModes say how subscribers hear about birthdays: a message per
+// birthday, or a digest every morning or every Monday morning.
+const (
+	DeliveryImmediate = "immediate"
+	DeliveryDaily     = "daily"
+	DeliveryWeekly    = "weekly"
+)
+
+var DeliveryModes = []string{DeliveryImmediate, DeliveryDaily, DeliveryWeekly}
+
+// DigestRecipient is an employee who gets digests in DeliveryMode.
+type DigestRecipient struct {
+	UserItem
+	DeliveryMode string
+}
+
+const (
+	SortByName     = "name"
+	SortByBirthday = "birthday"
+	SortByUpcoming = "upcoming"
+)
+
+// EmployeeFilter selects a page of employees. From and To are MM-DD days of
+// the year; a range with From after To wraps around the new year. Cursor,
+// when set, replaces Offset. Today is the MM-DD day of the viewer and
+// LeapDayKey the upcoming sort key of February 29 on it.
+type EmployeeFilter struct {
+	Query      string
+	Month      int
+	From       string
+	To         string
+	Sort       string
+	Today      string
+	LeapDayKey string
+	Offset     uint64
+	Limit      uint64
+	Cursor     *EmployeeCursor
+}
+
+// EmployeeCursor points after the last employee of a page in the order of
+// Sort.
+type EmployeeCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k"`
+	Id   uint64 `json:"id"`
+}
+
+type EmployeesPage struct {
+	Total      uint64      `json:"total"`
+	Items      []*UserItem `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// UpcomingBirthday is an employee with the date of the next birthday, the
+// age turned on it and the days left until it.
+type UpcomingBirthday struct {
+	UserItem
+	Date       string `json:"date"`
+	Age        int    `json:"age"`
+	DaysUntil  int    `json:"days_until"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+// FeedEntry is a birthday in the calendar feed. ChangedAt is the last time
+// the employee or the subscription changed.
+type FeedEntry struct {
+	Id         uint64
+	Login      string
+	Birthday   string
+	Visibility string
+	ChangedAt  time.Time
+}
+
+type FeedTokenResponse struct {
+	Token string `json:"token"`
+	Url   string `json:"url"`
+}
+
+// Subscription is a row of the subscriber table with the logins of both
+// sides.
+type Subscription struct {
+	SubscriberId    uint64
+	SubscriberLogin string
+	EmployeeId      uint64
+	EmployeeLogin   string
+	CreatedAt       time.Time
 }
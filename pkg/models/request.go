@@ -10,14 +10,86 @@ type SignupRequest struct {
 	Password string `json:"password"`
 	Email    string `json:"email"`
 	Birthday string `json:"birthday"`
+	Timezone string `json:"timezone"`
+	Locale   string `json:"locale"`
 }
 
+// ProfileRequest changes the fields that are set and keeps the others.
+type ProfileRequest struct {
+	Email      *string `json:"email"`
+	Birthday   *string `json:"birthday"`
+	Department *string `json:"department"`
+	Timezone   *string `json:"timezone"`
+	Locale     *string `json:"locale"`
+}
+
+// SubRequest subscribes to UserToId. Reminders are the days before the
+// birthday to be reminded on, 0 is the day itself and the default.
 type SubRequest struct {
 	UserFromId uint64 `json:"user_from_id"`
 	UserToId   uint64 `json:"user_to_id"`
+	Reminders  []int  `json:"reminders"`
 }
 
 type UnSubRequest struct {
 	UserFromId uint64 `json:"user_from_id"`
 	UserToId   uint64 `json:"user_to_id"`
 }
+
+type SettingsRequest struct {
+	Timezone        *string `json:"timezone"`
+	Locale          *string `json:"locale"`
+	HideSubscribers *bool   `json:"hide_subscribers"`
+	DeliveryMode    *string `json:"delivery_mode"`
+	Visibility      *string `json:"birthday_visibility"`
+	AllowSubscribe  *bool   `json:"allow_subscribe"`
+}
+
+type ChannelsRequest struct {
+	Channels []*Channel `json:"channels"`
+}
+
+type TemplateRequest struct {
+	Id uint64 `json:"id"`
+}
+
+type PreviewRequest struct {
+	Id      uint64        `json:"id"`
+	Subject string        `json:"subject"`
+	Body    string        `json:"body"`
+	Data    *TemplateData `json:"data"`
+}
+
+type SessionRequest struct {
+	Id string `json:"id"`
+}
+
+type TeamRequest struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+type TeamMemberRequest struct {
+	TeamId uint64 `json:"team_id"`
+	UserId uint64 `json:"user_id"`
+}
+
+type TeamSubRequest struct {
+	TeamId    uint64 `json:"team_id"`
+	Reminders []int  `json:"reminders"`
+}
+
+type ScheduleRequest struct {
+	Schedule string `json:"schedule"`
+}
+
+// TriggerRequest runs the birthday job for Date, a YYYY-MM-DD day.
+type TriggerRequest struct {
+	Date   string `json:"date"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type RoleRequest struct {
+	UserId uint64 `json:"user_id"`
+	Role   string `json:"role"`
+}
@@ -0,0 +1,37 @@
+package models
+
+const (
+	RoleEmployee = "employee"
+	RoleHR       = "hr"
+	RoleAdmin    = "admin"
+)
+
+type Permission string
+
+const (
+	PermManageEmployees Permission = "employees:manage"
+	PermManageTemplates Permission = "templates:manage"
+	PermManageWorker    Permission = "worker:manage"
+	PermManageRoles     Permission = "roles:manage"
+	PermImportEmployees Permission = "employees:import"
+	PermExportData      Permission = "data:export"
+	PermManageTeams     Permission = "teams:manage"
+)
+
+// RolePermissions lists what each role may do besides the actions every
+// authenticated employee has.
+var RolePermissions = map[string][]Permission{
+	RoleEmployee: {},
+	RoleHR:       {PermManageEmployees, PermManageTemplates, PermExportData, PermManageTeams},
+	RoleAdmin:    {PermManageEmployees, PermManageTemplates, PermManageWorker, PermManageRoles, PermImportEmployees, PermExportData, PermManageTeams},
+}
+
+func HasPermission(role string, perm Permission) bool {
+	for _, granted := range RolePermissions[role] {
+		if granted == perm {
+			return true
+		}
+	}
+
+	return false
+}
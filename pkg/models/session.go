@@ -6,4 +6,20 @@ type Session struct {
 	Login     string
 	SID       string
 	ExpiresAt time.Time
+	CreatedAt time.Time
+	LastSeen  time.Time
+	IP        string
+	UserAgent string
+}
+
+// SessionItem describes a session to its owner. Id is derived from the
+// session id, which itself is never shown.
+type SessionItem struct {
+	Id        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Current   bool      `json:"current"`
 }
@@ -0,0 +1,10 @@
+package models
+
+// Team is a department or any other group of employees. Subscribing to a
+// team notifies about the birthdays of all its current and future members.
+type Team struct {
+	Id         uint64 `json:"id"`
+	Name       string `json:"name"`
+	Members    uint64 `json:"members"`
+	Subscribed bool   `json:"subscribed"`
+}
@@ -0,0 +1,50 @@
+package models
+
+// Template is a greeting text in one locale. Name is the kind of message it
+// is used for, Subject and Body are Go templates executed with TemplateData.
+type Template struct {
+	Id      uint64 `json:"id"`
+	Name    string `json:"name"`
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	CardId  uint64 `json:"card_id"`
+}
+
+// Card is an image embedded into the HTML part of a greeting.
+type Card struct {
+	Id          uint64
+	ContentType string
+	Data        []byte
+}
+
+// TemplateNames lists the kinds of messages rendered from templates.
+var TemplateNames = []string{OutboxKindGreeting, OutboxKindNotify, OutboxKindReminder, OutboxKindDigest}
+
+// TemplateData holds the variables of templates. Birthdays is set for
+// digests only.
+type TemplateData struct {
+	Name       string        `json:"name"`
+	Age        int           `json:"age"`
+	Department string        `json:"department"`
+	DaysUntil  int           `json:"days_until"`
+	Birthdays  []*DigestItem `json:"birthdays"`
+	Date       string        `json:"date"`
+	Belated    bool          `json:"belated"`
+}
+
+// DigestItem is one birthday listed in a digest.
+type DigestItem struct {
+	Name       string `json:"name"`
+	Date       string `json:"date"`
+	Age        int    `json:"age"`
+	Department string `json:"department"`
+	DaysUntil  int    `json:"days_until"`
+}
+
+type RenderedMessage struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+	CardId  uint64 `json:"card_id"`
+}
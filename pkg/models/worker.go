@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// WorkerState is the schedule of the birthday job, a cron expression with
+// seconds, and whether the job is paused. NextRun is empty when paused.
+type WorkerState struct {
+	Schedule  string     `json:"schedule"`
+	Paused    bool       `json:"paused"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	NextRun   *time.Time `json:"next_run,omitempty"`
+}
+
+// PlannedMessage is a message produced by a manual run of the birthday job.
+// Queued is false in a dry run and for messages already in the outbox.
+type PlannedMessage struct {
+	RecipientId uint64 `json:"recipient_id"`
+	BirthdayId  uint64 `json:"birthday_id"`
+	Kind        string `json:"kind"`
+	Channel     string `json:"channel"`
+	To          string `json:"to"`
+	Subject     string `json:"subject"`
+	Body        string `json:"body"`
+	Queued      bool   `json:"queued"`
+}
+
+const (
+	TriggerPending = "pending"
+	TriggerRunning = "running"
+	TriggerDone    = "done"
+	TriggerFailed  = "failed"
+)
+
+// WorkerTrigger is a manual run of the birthday job for Date, a YYYY-MM-DD
+// day. The API
+// records it and the worker does it; Messages are filled in when it is done.
+type WorkerTrigger struct {
+	Id         uint64            `json:"id"`
+	Date       string            `json:"date"`
+	DryRun     bool              `json:"dry_run"`
+	Status     string            `json:"status"`
+	Messages   []*PlannedMessage `json:"messages"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"vk-rest/pkg/models"
+)
+
+// CardContentId is the Content-ID the HTML part uses to refer to the card.
+const CardContentId = "card"
+
+const htmlLayout = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; font-size: 16px;">
+%s<p>%s</p>
+</body>
+</html>
+`
+
+const htmlCard = `<p><img src="cid:` + CardContentId + `" alt="" style="max-width: 600px;"></p>
+`
+
+// SampleData is used for previews and to validate templates before saving.
+func SampleData() *models.TemplateData {
+	return &models.TemplateData{
+		Name:       "ivan",
+		Age:        30,
+		Department: "R&D",
+		DaysUntil:  0,
+		Date:       "2024-03-02",
+		Birthdays: []*models.DigestItem{
+			{Name: "maria", Date: "2024-03-04", Age: 25, Department: "QA", DaysUntil: 2},
+		},
+	}
+}
+
+// Validate renders tpl with SampleData, so both syntax errors and unknown
+// variables are reported.
+func Validate(tpl *models.Template) error {
+	_, err := Render(tpl, SampleData())
+	return err
+}
+
+// Render executes the template with data. The body is rendered once with
+// text/template for the plain part and once with html/template, which escapes
+// the values, for the HTML part, so both parts always carry the same text.
+func Render(tpl *models.Template, data *models.TemplateData) (*models.RenderedMessage, error) {
+	subject, err := executeText("subject", tpl.Subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := executeText("body", tpl.Body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTpl, err := htmltemplate.New("body").Parse(tpl.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse html body error: %s", err.Error())
+	}
+
+	var html bytes.Buffer
+	err = htmlTpl.Execute(&html, data)
+	if err != nil {
+		return nil, fmt.Errorf("execute html body error: %s", err.Error())
+	}
+
+	card := ""
+	if tpl.CardId != 0 {
+		card = htmlCard
+	}
+
+	return &models.RenderedMessage{
+		Subject: strings.TrimSpace(subject),
+		Text:    text,
+		HTML:    fmt.Sprintf(htmlLayout, card, strings.ReplaceAll(html.String(), "\n", "<br>\n")),
+		CardId:  tpl.CardId,
+	}, nil
+}
+
+func executeText(name, source string, data *models.TemplateData) (string, error) {
+	tpl, err := texttemplate.New(name).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("parse %s error: %s", name, err.Error())
+	}
+
+	var out bytes.Buffer
+	err = tpl.Execute(&out, data)
+	if err != nil {
+		return "", fmt.Errorf("execute %s error: %s", name, err.Error())
+	}
+
+	return out.String(), nil
+}
@@ -1,13 +1,224 @@
 package pkg
 
-var BirthdaySub = "INSERT INTO subscriber (id_subscribe_from, id_subscribe_to) VALUES ($1, $2)"
+var BirthdaySub = "INSERT INTO subscriber (id_subscribe_from, id_subscribe_to, reminders) VALUES ($1, $2, string_to_array($3, ',')::int[])"
+var UpdateReminders = "UPDATE subscriber SET reminders = string_to_array($3, ',')::int[] WHERE id_subscribe_from = $1 AND id_subscribe_to = $2"
 var BirthdayUnSub = "DELETE FROM subscriber WHERE id_subscribe_from = $1 AND id_subscribe_to = $2"
 
-var GetUser = "SELECT profile.id, profile.login, profile.email, profile.birthday FROM profile WHERE profile.login = $1 AND profile.password = $2"
+var GetUser = "SELECT profile.id, profile.login, profile.email, profile.birthday, profile.timezone, profile.locale, profile.department, profile.password FROM profile WHERE profile.login = $1"
+var UpdatePassword = "UPDATE profile SET password = $2 WHERE id = $1"
 var FindUser = "SELECT login FROM profile WHERE login = $1"
-var CreateUser = "INSERT INTO profile(login, password, email, birthday) VALUES($1, $2, $3, $4) RETURNING id"
+var CreateUser = "INSERT INTO profile(login, password, email, birthday, timezone, locale) VALUES($1, $2, $3, $4, $5, $6) RETURNING id"
+var ImportUser = "INSERT INTO profile(login, email, birthday, department) VALUES($1, $2, $3, $4) RETURNING id"
+var SetPasswordToken = `INSERT INTO password_token(profile_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	ON CONFLICT (profile_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at`
+var UsePasswordToken = "DELETE FROM password_token WHERE token_hash = $1 AND expires_at > now() RETURNING profile_id"
 var GetUserId = "SELECT profile.id FROM profile WHERE profile.login = $1"
-var GetEmployees = "SELECT profile.id, profile.login, profile.email, profile.birthday FROM profile OFFSET $1 LIMIT $2"
-var GetBirthdayEmployees = `SELECT id, login, email, birthday FROM profile WHERE EXTRACT(DAY FROM birthday) = EXTRACT(DAY FROM CURRENT_DATE) AND EXTRACT(MONTH FROM birthday) = EXTRACT(MONTH FROM CURRENT_DATE)`
-var GetEmployeeByBirthday = "SELECT p.id, p.login, p.email, p.birthday FROM profile p JOIN subscriber s ON p.id = s.id_subscribe_from WHERE s.id_subscribe_to = $1"
-var GetEmployeesBySubId = "SELECT users.id, user.login, user.email, user.birthday FROM users WHERE id_subscribe_to=$1"
+var GetEmployee = "SELECT id, login, email, birthday, timezone, locale, department, role, birthday_visibility FROM profile WHERE id = $1"
+var UpdateEmployee = `UPDATE profile SET email = COALESCE($2::text, email), birthday = COALESCE($3::date, birthday), department = COALESCE($4::text, department),
+	timezone = COALESCE($5::text, timezone), locale = COALESCE($6::text, locale), updated_at = now() WHERE id = $1`
+var DeleteEmployee = "DELETE FROM profile WHERE id = $1"
+var GetUserRole = "SELECT role FROM profile WHERE id = $1"
+var UpdateRole = "UPDATE profile SET role = $2 WHERE id = $1"
+
+// SearchEmployees is completed by the repository with the filter conditions
+// in %s. sort_key and id give the order and the keyset for cursors; total is
+// counted before the cursor condition.
+var SearchEmployees = `WITH filtered AS (
+	SELECT id, login, email, birthday, timezone, locale, department, birthday_visibility, %s AS sort_key, COUNT(*) OVER() AS total
+	FROM profile WHERE %s
+)
+SELECT id, login, email, birthday, timezone, locale, department, birthday_visibility, sort_key, total FROM filtered WHERE %s
+ORDER BY sort_key, id LIMIT %s OFFSET %s`
+
+// The birthday sorts put the employees who do not show that much of their
+// birthday last, so the order does not reveal it.
+var EmployeeSortByName = "login"
+var EmployeeSortByBirthday = "CASE WHEN birthday_visibility = 'full' THEN to_char(birthday, 'YYYY-MM-DD') ELSE '~' END"
+
+// EmployeeSortByUpcoming puts birthdays from today (MM-DD in the second %s) to
+// the end of the year before the ones that have already passed. February 29
+// takes the key in the first %s, which follows the leap day policy.
+var EmployeeSortByUpcoming = `CASE WHEN birthday_visibility = 'hidden' THEN '3'
+	WHEN to_char(birthday, 'MM-DD') = '02-29' THEN %s
+	WHEN to_char(birthday, 'MM-DD') < %s THEN '1' || to_char(birthday, 'MM-DD')
+	ELSE '0' || to_char(birthday, 'MM-DD') END`
+
+// EmployeeBirthdayShown keeps out the hidden birthdays when filtering by them.
+var EmployeeBirthdayShown = "birthday_visibility <> 'hidden'"
+
+// GetBirthdaysBetween selects employees born between two MM-DD days, with
+// a flag whether $1 gets their birthday, directly or through a team, the
+// same way the feed does. The range wraps around the new year when $2 is
+// after $3. Hidden birthdays are shown only to their owner.
+var GetBirthdaysBetween = `SELECT p.id, p.login, p.email, p.birthday, p.timezone, p.locale, p.department, p.birthday_visibility,
+	p.allow_subscribe AND EXISTS(SELECT 1 FROM subscriber s WHERE s.id_subscribe_from = $1 AND s.id_subscribe_to = p.id
+		UNION SELECT 1 FROM team_subscriber ts JOIN team_member tm ON tm.team_id = ts.team_id WHERE ts.profile_id = $1 AND tm.profile_id = p.id)
+	FROM profile p
+	WHERE (p.birthday_visibility <> 'hidden' OR p.id = $1) AND CASE WHEN $2::text <= $3::text THEN to_char(p.birthday, 'MM-DD') BETWEEN $2 AND $3
+	ELSE to_char(p.birthday, 'MM-DD') >= $2 OR to_char(p.birthday, 'MM-DD') <= $3 END`
+
+var GetBirthdayEmployees = `SELECT id, login, email, birthday, timezone, locale, department, birthday_visibility FROM profile WHERE timezone = $1 AND to_char(birthday, 'MM-DD') = ANY(string_to_array($2, ','))`
+var GetTimezones = "SELECT DISTINCT timezone FROM profile"
+var UpdateTimezone = "UPDATE profile SET timezone = $2 WHERE id = $1"
+var UpdateLocale = "UPDATE profile SET locale = $2 WHERE id = $1"
+var UpdateHideSubscribers = "UPDATE profile SET hide_subscribers = $2 WHERE id = $1"
+var UpdateDeliveryMode = "UPDATE profile SET delivery_mode = $2 WHERE id = $1"
+var UpdateBirthdayVisibility = "UPDATE profile SET birthday_visibility = $2, updated_at = now() WHERE id = $1"
+var UpdateAllowSubscribe = "UPDATE profile SET allow_subscribe = $2, updated_at = now() WHERE id = $1"
+var GetPrivacy = "SELECT birthday_visibility, allow_subscribe FROM profile WHERE id = $1"
+var GetDigestRecipients = `SELECT id, login, email, birthday, timezone, locale, department, delivery_mode FROM profile
+	WHERE timezone = $1 AND delivery_mode = ANY(string_to_array($2, ','))`
+var GetHideSubscribers = "SELECT hide_subscribers FROM profile WHERE id = $1"
+
+// GetEmployeeByBirthday returns everyone to remind about $1 $2 days before
+// the birthday: the direct subscribers and the subscribers of the teams $1
+// is a member of whose subscription has that reminder. Each employee is
+// returned once however many subscriptions lead to them. Employees who get
+// digests have the birthday itself in the digest, so they are left out of
+// the day's notify ($2 = 0) but still get the earlier reminders. Nobody is
+// returned when $1 hides the birthday or refuses subscriptions.
+var GetEmployeeByBirthday = `SELECT p.id, p.login, p.email, p.birthday, p.timezone, p.locale, p.department FROM profile p
+	WHERE p.id <> $1 AND ($2 > 0 OR p.delivery_mode = 'immediate')
+	AND EXISTS(SELECT 1 FROM profile c WHERE c.id = $1 AND c.birthday_visibility <> 'hidden' AND c.allow_subscribe)
+	AND p.id IN (
+		SELECT s.id_subscribe_from FROM subscriber s WHERE s.id_subscribe_to = $1 AND $2 = ANY(s.reminders)
+		UNION
+		SELECT ts.profile_id FROM team_subscriber ts JOIN team_member tm ON tm.team_id = ts.team_id
+		WHERE tm.profile_id = $1 AND $2 = ANY(ts.reminders)
+	)`
+
+// GetSubscribedEmployees returns everyone $1 follows directly or through a
+// team, once each, except for those who hide the birthday or refuse
+// subscriptions.
+var GetSubscribedEmployees = `SELECT p.id, p.login, p.email, p.birthday, p.timezone, p.locale, p.department, p.birthday_visibility FROM profile p
+	WHERE p.id <> $1 AND p.birthday_visibility <> 'hidden' AND p.allow_subscribe AND p.id IN (
+		SELECT s.id_subscribe_to FROM subscriber s WHERE s.id_subscribe_from = $1
+		UNION
+		SELECT tm.profile_id FROM team_subscriber ts JOIN team_member tm ON tm.team_id = ts.team_id WHERE ts.profile_id = $1
+	) ORDER BY p.login`
+
+// GetReminderDays returns every reminder offset some subscription has.
+var GetReminderDays = `SELECT DISTINCT d FROM (
+		SELECT unnest(reminders) AS d FROM subscriber
+		UNION ALL
+		SELECT unnest(reminders) FROM team_subscriber
+	) r ORDER BY d`
+var GetChannels = "SELECT channel, address FROM profile_channel WHERE profile_id = $1 ORDER BY channel"
+var DeleteChannels = "DELETE FROM profile_channel WHERE profile_id = $1"
+var AddChannel = "INSERT INTO profile_channel(profile_id, channel, address) VALUES($1, $2, $3)"
+
+// GetEmployeesBySubId returns a page of everyone $1 follows directly or
+// through a team, once each, except for those who hide the birthday or
+// refuse subscriptions.
+var GetEmployeesBySubId = `SELECT p.id, p.login, p.email, p.birthday, p.timezone, p.locale, p.department, p.birthday_visibility, COUNT(*) OVER()
+	FROM profile p WHERE p.id <> $1 AND p.birthday_visibility <> 'hidden' AND p.allow_subscribe AND p.id IN (
+		SELECT s.id_subscribe_to FROM subscriber s WHERE s.id_subscribe_from = $1
+		UNION
+		SELECT tm.profile_id FROM team_subscriber ts JOIN team_member tm ON tm.team_id = ts.team_id WHERE ts.profile_id = $1
+	) ORDER BY p.login, p.id OFFSET $2 LIMIT $3`
+
+// GetSubscribers returns a page of everyone following $1 directly or through
+// a team, once each. Nobody is returned when $1 hides the birthday or
+// refuses subscriptions, as nobody is notified about it then.
+var GetSubscribers = `SELECT p.id, p.login, p.email, p.birthday, p.timezone, p.locale, p.department, p.birthday_visibility, COUNT(*) OVER()
+	FROM profile p WHERE p.id <> $1
+	AND EXISTS(SELECT 1 FROM profile c WHERE c.id = $1 AND c.birthday_visibility <> 'hidden' AND c.allow_subscribe)
+	AND p.id IN (
+		SELECT s.id_subscribe_from FROM subscriber s WHERE s.id_subscribe_to = $1
+		UNION
+		SELECT ts.profile_id FROM team_subscriber ts JOIN team_member tm ON tm.team_id = ts.team_id WHERE tm.profile_id = $1
+	) ORDER BY p.login, p.id OFFSET $2 LIMIT $3`
+
+// EnqueueOutbox skips the message when $11 is a fencing token older than
+// the one of the current worker leader. Zero means the message is not fenced.
+var EnqueueOutbox = `INSERT INTO outbox(recipient_id, birthday_id, year, kind, channel, address, subject, body, body_html, card_id)
+	SELECT $1::int, $2::int, $3::int, $4::text, $5::text, $6::text, $7::text, $8::text, $9::text, NULLIF($10::int, 0)
+	WHERE $11::bigint = 0 OR NOT EXISTS (SELECT 1 FROM worker_state WHERE fence > $11::bigint)
+	ON CONFLICT (recipient_id, birthday_id, year, kind, channel) DO NOTHING`
+var ClaimOutbox = `UPDATE outbox SET next_attempt_at = $2 WHERE id IN (
+		SELECT id FROM outbox WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+	) RETURNING id, recipient_id, birthday_id, year, kind, channel, address, subject, body, body_html, COALESCE(card_id, 0), attempts`
+var MarkOutboxSent = "UPDATE outbox SET status = 'sent', attempts = attempts + 1, sent_at = $2, last_error = '' WHERE id = $1"
+var MarkOutboxFailed = "UPDATE outbox SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_error = $4 WHERE id = $1"
+var AddOutboxAttempt = "INSERT INTO outbox_attempt(outbox_id, attempted_at, success, error) VALUES($1, $2, $3, $4)"
+
+var GetTemplates = "SELECT id, name, locale, subject, body, COALESCE(card_id, 0) FROM template ORDER BY name, locale"
+var GetTemplate = "SELECT id, name, locale, subject, body, COALESCE(card_id, 0) FROM template WHERE id = $1"
+var FindTemplate = "SELECT id, name, locale, subject, body, COALESCE(card_id, 0) FROM template WHERE name = $1 AND locale = $2"
+var CreateTemplate = "INSERT INTO template(name, locale, subject, body) VALUES($1, $2, $3, $4) RETURNING id"
+var UpdateTemplate = "UPDATE template SET name = $2, locale = $3, subject = $4, body = $5, updated_at = now() WHERE id = $1"
+var DeleteTemplate = "DELETE FROM template WHERE id = $1"
+var CreateCard = "INSERT INTO card(content_type, data) VALUES($1, $2) RETURNING id"
+var GetCard = "SELECT id, content_type, data FROM card WHERE id = $1"
+var SetTemplateCard = "UPDATE template SET card_id = NULLIF($2, 0), updated_at = now() WHERE id = $1"
+
+var SetFeedToken = `INSERT INTO feed_token(profile_id, token_hash) VALUES ($1, $2)
+	ON CONFLICT (profile_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = now()`
+var DeleteFeedToken = "DELETE FROM feed_token WHERE profile_id = $1"
+var GetFeedToken = "SELECT profile_id FROM feed_token WHERE token_hash = $1"
+var GetFeedEntries = `SELECT p.id, p.login, p.birthday, p.birthday_visibility, GREATEST(p.updated_at, MAX(x.created_at)) FROM (
+		SELECT s.id_subscribe_to AS id, s.created_at FROM subscriber s WHERE s.id_subscribe_from = $1
+		UNION ALL
+		SELECT tm.profile_id, GREATEST(tm.created_at, ts.created_at)
+		FROM team_subscriber ts JOIN team_member tm ON tm.team_id = ts.team_id WHERE ts.profile_id = $1 AND tm.profile_id <> $1
+	) x JOIN profile p ON p.id = x.id WHERE p.birthday_visibility <> 'hidden' AND p.allow_subscribe GROUP BY p.id ORDER BY p.id`
+
+var ExportEmployees = "SELECT id, login, email, birthday, timezone, locale, department, role FROM profile ORDER BY id"
+var ExportSubscriptions = `SELECT s.id_subscribe_from, f.login, s.id_subscribe_to, t.login, s.created_at
+	FROM subscriber s JOIN profile f ON f.id = s.id_subscribe_from JOIN profile t ON t.id = s.id_subscribe_to
+	ORDER BY s.id_subscribe_from, s.id_subscribe_to`
+
+var GetTeams = `SELECT t.id, t.name, (SELECT COUNT(*) FROM team_member m WHERE m.team_id = t.id),
+	EXISTS(SELECT 1 FROM team_subscriber s WHERE s.team_id = t.id AND s.profile_id = $1)
+	FROM team t ORDER BY t.name`
+var TeamExists = "SELECT EXISTS(SELECT 1 FROM team WHERE id = $1)"
+var CreateTeam = "INSERT INTO team(name) VALUES($1) RETURNING id"
+var UpdateTeam = "UPDATE team SET name = $2 WHERE id = $1"
+var DeleteTeam = "DELETE FROM team WHERE id = $1"
+var GetTeamMembers = `SELECT p.id, p.login, p.email, p.birthday, p.timezone, p.locale, p.department, p.birthday_visibility, COUNT(*) OVER()
+	FROM team_member m JOIN profile p ON p.id = m.profile_id WHERE m.team_id = $1 ORDER BY p.login, p.id OFFSET $2 LIMIT $3`
+var AddTeamMember = "INSERT INTO team_member(team_id, profile_id) VALUES($1, $2) ON CONFLICT DO NOTHING"
+var RemoveTeamMember = "DELETE FROM team_member WHERE team_id = $1 AND profile_id = $2"
+var TeamSub = "INSERT INTO team_subscriber(team_id, profile_id, reminders) VALUES($1, $2, string_to_array($3, ',')::int[])"
+var UpdateTeamReminders = "UPDATE team_subscriber SET reminders = string_to_array($3, ',')::int[] WHERE team_id = $1 AND profile_id = $2"
+var TeamUnSub = "DELETE FROM team_subscriber WHERE team_id = $1 AND profile_id = $2"
+
+// worker_state has a single row.
+var GetWorkerState = "SELECT schedule, paused, updated_at FROM worker_state"
+var UpdateWorkerSchedule = "INSERT INTO worker_state(schedule) VALUES ($1) ON CONFLICT (id) DO UPDATE SET schedule = $1, updated_at = now()"
+var UpdateWorkerPaused = "INSERT INTO worker_state(schedule, paused) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET paused = $2, updated_at = now()"
+
+// worker_run keeps the last local date the birthday job was done for in
+// every time zone. A date never moves back.
+var GetLastRuns = "SELECT timezone, last_run_date FROM worker_run"
+var SetLastRun = "INSERT INTO worker_run(timezone, last_run_date) VALUES ($1, $2) ON CONFLICT (timezone) DO UPDATE SET last_run_date = GREATEST(worker_run.last_run_date, $2)"
+
+// NextFence issues a fencing token to a new worker leader. Messages fenced
+// with an older token are not queued any more.
+var NextFence = "INSERT INTO worker_state(schedule, fence) VALUES ($1, 1) ON CONFLICT (id) DO UPDATE SET fence = worker_state.fence + 1 RETURNING fence"
+
+// worker_trigger keeps the manual runs of the birthday job asked for through
+// the API until the worker has done them. ClaimTrigger takes the oldest
+// pending run, or a running one started before $1 by a worker that died.
+var AddTrigger = "INSERT INTO worker_trigger(date, dry_run) VALUES ($1, $2) RETURNING id"
+var GetTrigger = `SELECT id, date, dry_run, status, COALESCE(messages, '[]'), error, created_at, finished_at
+	FROM worker_trigger WHERE id = $1`
+var HasOpenTriggers = "SELECT EXISTS(SELECT 1 FROM worker_trigger WHERE status IN ('pending', 'running'))"
+var ClaimTrigger = `UPDATE worker_trigger SET status = 'running', started_at = now() WHERE id = (
+		SELECT id FROM worker_trigger WHERE status = 'pending' OR (status = 'running' AND started_at < $1)
+		ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+	) RETURNING id, date, dry_run, created_at`
+var FinishTrigger = "UPDATE worker_trigger SET status = $2, messages = $3, error = $4, finished_at = now() WHERE id = $1"
+
+// schema_version lists the applied migrations. LockSchema serializes
+// migrators started at the same time until their transaction ends.
+var CreateSchemaVersion = `CREATE TABLE IF NOT EXISTS schema_version(
+	version INT NOT NULL PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+var LockSchema = "SELECT pg_advisory_xact_lock(hashtext('schema_version'))"
+var GetSchemaVersions = "SELECT version, name, applied_at FROM schema_version ORDER BY version"
+var SchemaVersionApplied = "SELECT EXISTS(SELECT 1 FROM schema_version WHERE version = $1)"
+var AddSchemaVersion = "INSERT INTO schema_version(version, name) VALUES ($1, $2)"
+var DeleteSchemaVersion = "DELETE FROM schema_version WHERE version = $1"
@@ -1,28 +1,237 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/sha512"
-	"math/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx"
+	"golang.org/x/crypto/argon2"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+const argonPrefix = "$argon2id$"
+
+// HashPassword returns a salted argon2id hash in the PHC string format
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>, so the algorithm and its
+// parameters are stored together with the hash.
+func HashPassword(password string) ([]byte, error) {
+	salt := make([]byte, argonSaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generate salt error: %s", err.Error())
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argonPrefix, argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	return []byte(encoded), nil
+}
+
+// CheckPassword compares password with the stored hash in constant time.
+// Hashes without the argon2id prefix are unsalted SHA-512 from older
+// versions. rehash is true when the password matched but the hash should be
+// replaced with one from HashPassword.
+func CheckPassword(password string, hash []byte) (ok bool, rehash bool) {
+	if !bytes.HasPrefix(hash, []byte(argonPrefix)) {
+		legacy := sha512.Sum512([]byte(password))
+		ok = subtle.ConstantTimeCompare(legacy[:], hash) == 1
+		return ok, ok
+	}
+
+	var version, memory, iterations, threads int
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return false, false
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads)
+	if err != nil || memory <= 0 || iterations <= 0 || threads <= 0 || threads > 255 {
+		return false, false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
 
-func HashPassword(password string) []byte {
-	hashPassword := sha512.Sum512([]byte(password))
-	passwordByteSlice := hashPassword[:]
-	return passwordByteSlice
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, uint32(iterations), uint32(memory), uint8(threads), uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return false, false
+	}
+
+	return true, memory != argonMemory || iterations != argonTime || threads != argonThreads || len(key) != argonKeyLen
 }
 
-func RandStringRunes(seed int) string {
-	symbols := make([]rune, seed)
-	for i := range symbols {
-		symbols[i] = letterRunes[rand.Intn(len(letterRunes))]
+// RandToken returns size bytes from crypto/rand encoded as URL-safe base64.
+func RandToken(size int) (string, error) {
+	token := make([]byte, size)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", fmt.Errorf("generate token error: %s", err.Error())
 	}
-	return string(symbols)
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
 }
 
 const MaxRetries = 5
 
-var HeaderBirthdayEmp = "Поздравление"
-var BodyBirthdayFromEmp = "Сегодня день рождения у %s, не забудьте поздравить его!"
-var BodyBirthdayToEmp = "Поздравляем Вас с днём рождения!"
+const DefaultTimezone = "UTC"
+const DefaultLocale = "ru"
+
+// DefaultSchedule runs the birthday job every 15 minutes. It is used until
+// an admin sets another cron expression.
+const DefaultSchedule = "0 */15 * * * *"
+
+// MaxReminderDays is how many days before a birthday a reminder may come,
+// MaxReminders how many reminders one subscription may have.
+const (
+	MaxReminderDays = 60
+	MaxReminders    = 5
+)
+
+var localeRegexp = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// ValidTimezone reports whether tz is an IANA time zone name. "Local" is
+// rejected because it means the server's zone, not the employee's.
+func ValidTimezone(tz string) bool {
+	if tz == "" || tz == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(tz)
+	return err == nil
+}
+
+// PublicId derives a short identifier from a secret, so it can be shown
+// without revealing the secret.
+func PublicId(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ValidLocale reports whether locale looks like "en" or "en-US".
+func ValidLocale(locale string) bool {
+	return localeRegexp.MatchString(locale)
+}
+
+// NormalizeReminders returns the distinct reminder offsets in days, the
+// earliest reminder first, and false if an offset is out of range or there
+// are too many. No offsets mean a reminder on the day only.
+func NormalizeReminders(days []int) ([]int, bool) {
+	if len(days) == 0 {
+		return []int{0}, true
+	}
+
+	days = slices.Clone(days)
+	slices.Sort(days)
+	days = slices.Compact(days)
+	slices.Reverse(days)
+
+	if len(days) > MaxReminders || days[0] > MaxReminderDays || days[len(days)-1] < 0 {
+		return nil, false
+	}
+
+	return days, true
+}
+
+// JoinInts formats values for string_to_array in queries.
+func JoinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, strconv.Itoa(value))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// ValidEmail reports whether email is a bare address like "ivan@example.com".
+func ValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// ValidBirthday reports whether birthday is a YYYY-MM-DD date that is not in
+// the future.
+func ValidBirthday(birthday string) bool {
+	date, err := time.Parse(time.DateOnly, birthday)
+	return err == nil && !date.After(time.Now())
+}
+
+// ParseDate parses a date column scanned into a string, which may carry a
+// time part.
+func ParseDate(date string) (time.Time, error) {
+	if len(date) > len(time.DateOnly) {
+		date = date[:len(time.DateOnly)]
+	}
+
+	return time.Parse(time.DateOnly, date)
+}
+
+// EncodeCursor packs a pagination cursor into an opaque URL-safe string.
+func EncodeCursor(cursor any) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor error: %s", err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor unpacks a cursor made by EncodeCursor into cursor.
+func DecodeCursor(encoded string, cursor any) error {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("decode cursor error: %s", err.Error())
+	}
+
+	err = json.Unmarshal(data, cursor)
+	if err != nil {
+		return fmt.Errorf("decode cursor error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// IsUniqueViolation reports whether err is a Postgres unique constraint error.
+func IsUniqueViolation(err error) bool {
+	var pgErr pgx.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// IsForeignKeyViolation reports whether err is a Postgres foreign key error,
+// that is the referenced row does not exist.
+func IsForeignKeyViolation(err error) bool {
+	var pgErr pgx.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -0,0 +1,140 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const rootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const workbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const sheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const sheetEnd = `</sheetData></worksheet>`
+
+// Writer streams a workbook with a single sheet. The fixed parts are written
+// first and the sheet is written last, row by row, so no rows are kept in
+// memory.
+type Writer struct {
+	zw    *zip.Writer
+	sheet io.Writer
+}
+
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	zw := zip.NewWriter(w)
+
+	var name bytes.Buffer
+	err := xml.EscapeText(&name, []byte(sheetName))
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("create %s error: %s", part.name, err.Error())
+		}
+
+		_, err = io.WriteString(f, part.content)
+		if err != nil {
+			return nil, fmt.Errorf("write %s error: %s", part.name, err.Error())
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("create sheet error: %s", err.Error())
+	}
+
+	_, err = io.WriteString(sheet, sheetStart)
+	if err != nil {
+		return nil, fmt.Errorf("write sheet error: %s", err.Error())
+	}
+
+	return &Writer{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Integers become numeric cells, booleans boolean
+// cells and everything else inline strings.
+func (w *Writer) WriteRow(values []any) error {
+	_, err := io.WriteString(w.sheet, "<row>")
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		switch v := value.(type) {
+		case int:
+			_, err = fmt.Fprintf(w.sheet, `<c t="n"><v>%d</v></c>`, v)
+		case uint64:
+			_, err = fmt.Fprintf(w.sheet, `<c t="n"><v>%d</v></c>`, v)
+		case bool:
+			cell := `<c t="b"><v>0</v></c>`
+			if v {
+				cell = `<c t="b"><v>1</v></c>`
+			}
+			_, err = io.WriteString(w.sheet, cell)
+		default:
+			_, err = io.WriteString(w.sheet, `<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err == nil {
+				err = xml.EscapeText(w.sheet, []byte(fmt.Sprint(v)))
+			}
+			if err == nil {
+				_, err = io.WriteString(w.sheet, `</t></is></c>`)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w.sheet, "</row>")
+	return err
+}
+
+// Close finishes the sheet and the archive.
+func (w *Writer) Close() error {
+	_, err := io.WriteString(w.sheet, sheetEnd)
+	if err != nil {
+		return err
+	}
+
+	return w.zw.Close()
+}
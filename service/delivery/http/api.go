@@ -1,19 +1,55 @@
 package delivery
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	utils "vk-rest/pkg"
 	errs "vk-rest/pkg/errors"
+	"vk-rest/pkg/export"
+	"vk-rest/pkg/ical"
 	"vk-rest/pkg/middleware"
 	"vk-rest/pkg/models"
 	httpResponse "vk-rest/pkg/response"
 	"vk-rest/service/usecase/core"
 )
 
+const maxCardSize = 1 << 20
+
+const maxEmployeesLimit = 100
+
+const (
+	maxImportSize = 5 << 20
+	maxImportRows = 5000
+)
+
+const feedPath = "/api/v1/birthdays/feed.ics"
+
+const (
+	defaultUpcomingDays = 30
+	maxUpcomingDays     = 366
+)
+
+var cardContentTypes = []string{"image/png", "image/jpeg", "image/gif"}
+
 //go:generate mockgen -source=api.go -destination=.mocks/http_api_mock.go -package=mocks
 type IApi interface {
 	Signin(w http.ResponseWriter, r *http.Request)
@@ -21,25 +57,84 @@ type IApi interface {
 	Logout(w http.ResponseWriter, r *http.Request)
 	AuthAccept(w http.ResponseWriter, r *http.Request)
 	GetEmployees(w http.ResponseWriter, r *http.Request)
+	GetEmployee(w http.ResponseWriter, r *http.Request)
+	UpdateEmployee(w http.ResponseWriter, r *http.Request)
+	DeleteEmployee(w http.ResponseWriter, r *http.Request)
+	ImportEmployees(w http.ResponseWriter, r *http.Request)
+	SetPassword(w http.ResponseWriter, r *http.Request)
+	ExportEmployees(w http.ResponseWriter, r *http.Request)
+	ExportSubscriptions(w http.ResponseWriter, r *http.Request)
+	GetMe(w http.ResponseWriter, r *http.Request)
+	UpdateMe(w http.ResponseWriter, r *http.Request)
+	GetUpcomingBirthdays(w http.ResponseWriter, r *http.Request)
+	GetBirthdayCalendar(w http.ResponseWriter, r *http.Request)
+	GetFeed(w http.ResponseWriter, r *http.Request)
+	CreateFeedToken(w http.ResponseWriter, r *http.Request)
+	RevokeFeedToken(w http.ResponseWriter, r *http.Request)
 	BirthdaySub(w http.ResponseWriter, r *http.Request)
+	UpdateReminders(w http.ResponseWriter, r *http.Request)
 	BirthdayUnSub(w http.ResponseWriter, r *http.Request)
+	GetSubscriptions(w http.ResponseWriter, r *http.Request)
+	GetSubscribers(w http.ResponseWriter, r *http.Request)
+	GetTeams(w http.ResponseWriter, r *http.Request)
+	CreateTeam(w http.ResponseWriter, r *http.Request)
+	UpdateTeam(w http.ResponseWriter, r *http.Request)
+	DeleteTeam(w http.ResponseWriter, r *http.Request)
+	GetTeamMembers(w http.ResponseWriter, r *http.Request)
+	AddTeamMember(w http.ResponseWriter, r *http.Request)
+	RemoveTeamMember(w http.ResponseWriter, r *http.Request)
+	TeamSub(w http.ResponseWriter, r *http.Request)
+	UpdateTeamReminders(w http.ResponseWriter, r *http.Request)
+	TeamUnSub(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
+	GetChannels(w http.ResponseWriter, r *http.Request)
+	SetChannels(w http.ResponseWriter, r *http.Request)
+	GetTemplates(w http.ResponseWriter, r *http.Request)
+	CreateTemplate(w http.ResponseWriter, r *http.Request)
+	UpdateTemplate(w http.ResponseWriter, r *http.Request)
+	DeleteTemplate(w http.ResponseWriter, r *http.Request)
+	PreviewTemplate(w http.ResponseWriter, r *http.Request)
+	UploadTemplateCard(w http.ResponseWriter, r *http.Request)
+	DeleteTemplateCard(w http.ResponseWriter, r *http.Request)
+	GetSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeAllSessions(w http.ResponseWriter, r *http.Request)
+	SetRole(w http.ResponseWriter, r *http.Request)
+	GetWorkerState(w http.ResponseWriter, r *http.Request)
+	SetWorkerSchedule(w http.ResponseWriter, r *http.Request)
+	PauseWorker(w http.ResponseWriter, r *http.Request)
+	ResumeWorker(w http.ResponseWriter, r *http.Request)
+	TriggerWorker(w http.ResponseWriter, r *http.Request)
+	GetWorkerTrigger(w http.ResponseWriter, r *http.Request)
 }
 
 type Api struct {
-	log     *logrus.Logger
-	mx      *http.ServeMux
-	profile usecase.IProfileCore
-	session usecase.ISessionCore
-	sub     usecase.ISubCore
+	log      *logrus.Logger
+	mx       *http.ServeMux
+	profile  usecase.IProfileCore
+	session  usecase.ISessionCore
+	sub      usecase.ISubCore
+	team     usecase.ITeamCore
+	birthday usecase.IBirthdayCore
+	feed     usecase.IFeedCore
+	export   usecase.IExportCore
+	template usecase.ITemplateCore
+	worker   usecase.IWorkerCore
 }
 
 func GetApi(core *usecase.Core, log *logrus.Logger) *Api {
 	api := &Api{
-		profile: core,
-		session: core,
-		sub:     core,
-		log:     log,
-		mx:      http.NewServeMux(),
+		profile:  core,
+		session:  core,
+		sub:      core,
+		team:     core,
+		birthday: core,
+		feed:     core,
+		export:   core,
+		template: core,
+		worker:   core,
+		log:      log,
+		mx:       http.NewServeMux(),
 	}
 
 	md := &middleware.Middleware{
@@ -52,8 +147,79 @@ func GetApi(core *usecase.Core, log *logrus.Logger) *Api {
 	api.mx.Handle("/logout", md.MethodCheck(http.HandlerFunc(api.Logout), http.MethodDelete))
 	api.mx.Handle("/authcheck", md.MethodCheck(http.HandlerFunc(api.AuthAccept), http.MethodGet))
 	api.mx.Handle("/api/v1/employees", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.GetEmployees), http.MethodGet)))
-	api.mx.Handle("/api/v1/birthday/subscribe", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.BirthdaySub), http.MethodPost)))
+	api.mx.Handle("/api/v1/employees/", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:    http.HandlerFunc(api.GetEmployee),
+		http.MethodPatch:  http.HandlerFunc(api.UpdateEmployee),
+		http.MethodDelete: http.HandlerFunc(api.DeleteEmployee),
+	})))
+	api.mx.Handle("/api/v1/employees/import", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.ImportEmployees), http.MethodPost), models.PermImportEmployees)))
+	api.mx.Handle("/api/v1/password/set", md.MethodCheck(http.HandlerFunc(api.SetPassword), http.MethodPost))
+	api.mx.Handle("/api/v1/export/employees", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.ExportEmployees), http.MethodGet), models.PermExportData)))
+	api.mx.Handle("/api/v1/export/subscriptions", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.ExportSubscriptions), http.MethodGet), models.PermExportData)))
+	api.mx.Handle("/api/v1/me", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:   http.HandlerFunc(api.GetMe),
+		http.MethodPatch: http.HandlerFunc(api.UpdateMe),
+	})))
+	api.mx.Handle("/api/v1/birthdays/upcoming", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.GetUpcomingBirthdays), http.MethodGet)))
+	api.mx.Handle("/api/v1/birthdays/calendar", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.GetBirthdayCalendar), http.MethodGet)))
+	api.mx.Handle(feedPath, md.MethodCheck(http.HandlerFunc(api.GetFeed), http.MethodGet))
+	api.mx.Handle("/api/v1/birthdays/feed/token", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodPost:   http.HandlerFunc(api.CreateFeedToken),
+		http.MethodDelete: http.HandlerFunc(api.RevokeFeedToken),
+	})))
+	api.mx.Handle("/api/v1/birthday/subscribe", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodPost: http.HandlerFunc(api.BirthdaySub),
+		http.MethodPut:  http.HandlerFunc(api.UpdateReminders),
+	})))
 	api.mx.Handle("/api/v1/birthday/unsubscribe", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.BirthdayUnSub), http.MethodDelete)))
+	api.mx.Handle("/api/v1/birthday/subscriptions", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.GetSubscriptions), http.MethodGet)))
+	api.mx.Handle("/api/v1/birthday/subscribers", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.GetSubscribers), http.MethodGet)))
+	api.mx.Handle("/api/v1/teams", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:    http.HandlerFunc(api.GetTeams),
+		http.MethodPost:   md.PermissionCheck(http.HandlerFunc(api.CreateTeam), models.PermManageTeams),
+		http.MethodPut:    md.PermissionCheck(http.HandlerFunc(api.UpdateTeam), models.PermManageTeams),
+		http.MethodDelete: md.PermissionCheck(http.HandlerFunc(api.DeleteTeam), models.PermManageTeams),
+	})))
+	api.mx.Handle("/api/v1/teams/members", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:    http.HandlerFunc(api.GetTeamMembers),
+		http.MethodPost:   md.PermissionCheck(http.HandlerFunc(api.AddTeamMember), models.PermManageTeams),
+		http.MethodDelete: md.PermissionCheck(http.HandlerFunc(api.RemoveTeamMember), models.PermManageTeams),
+	})))
+	api.mx.Handle("/api/v1/teams/subscribe", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodPost: http.HandlerFunc(api.TeamSub),
+		http.MethodPut:  http.HandlerFunc(api.UpdateTeamReminders),
+	})))
+	api.mx.Handle("/api/v1/teams/unsubscribe", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.TeamUnSub), http.MethodDelete)))
+	api.mx.Handle("/api/v1/settings", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.UpdateSettings), http.MethodPost)))
+	api.mx.Handle("/api/v1/channels", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:  http.HandlerFunc(api.GetChannels),
+		http.MethodPost: http.HandlerFunc(api.SetChannels),
+	})))
+	api.mx.Handle("/api/v1/sessions", md.AuthCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:    http.HandlerFunc(api.GetSessions),
+		http.MethodDelete: http.HandlerFunc(api.RevokeAllSessions),
+	})))
+	api.mx.Handle("/api/v1/sessions/revoke", md.AuthCheck(md.MethodCheck(http.HandlerFunc(api.RevokeSession), http.MethodDelete)))
+	api.mx.Handle("/api/v1/templates", md.AuthCheck(md.PermissionCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:    http.HandlerFunc(api.GetTemplates),
+		http.MethodPost:   http.HandlerFunc(api.CreateTemplate),
+		http.MethodPut:    http.HandlerFunc(api.UpdateTemplate),
+		http.MethodDelete: http.HandlerFunc(api.DeleteTemplate),
+	}), models.PermManageTemplates)))
+	api.mx.Handle("/api/v1/templates/card", md.AuthCheck(md.PermissionCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodPost:   http.HandlerFunc(api.UploadTemplateCard),
+		http.MethodDelete: http.HandlerFunc(api.DeleteTemplateCard),
+	}), models.PermManageTemplates)))
+	api.mx.Handle("/api/v1/templates/preview", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.PreviewTemplate), http.MethodPost), models.PermManageTemplates)))
+	api.mx.Handle("/api/v1/roles", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.SetRole), http.MethodPost), models.PermManageRoles)))
+	api.mx.Handle("/api/v1/worker", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.GetWorkerState), http.MethodGet), models.PermManageWorker)))
+	api.mx.Handle("/api/v1/worker/schedule", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.SetWorkerSchedule), http.MethodPut), models.PermManageWorker)))
+	api.mx.Handle("/api/v1/worker/pause", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.PauseWorker), http.MethodPost), models.PermManageWorker)))
+	api.mx.Handle("/api/v1/worker/resume", md.AuthCheck(md.PermissionCheck(md.MethodCheck(http.HandlerFunc(api.ResumeWorker), http.MethodPost), models.PermManageWorker)))
+	api.mx.Handle("/api/v1/worker/trigger", md.AuthCheck(md.PermissionCheck(md.MethodsCheck(map[string]http.Handler{
+		http.MethodGet:  http.HandlerFunc(api.GetWorkerTrigger),
+		http.MethodPost: http.HandlerFunc(api.TriggerWorker),
+	}), models.PermManageWorker)))
 
 	return api
 }
@@ -101,7 +267,14 @@ func (a *Api) Signin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	session, _ := a.session.CreateSession(r.Context(), request.Login)
+	session, err := a.session.CreateSession(r.Context(), request.Login, clientIP(r), r.UserAgent())
+	if err != nil {
+		a.log.Error("Signin error: ", err.Error())
+		response := models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}
+		httpResponse.SendResponse(w, r, &response, a.log)
+		return
+	}
+
 	cookie := &http.Cookie{
 		Name:     "session_id",
 		Value:    session.SID,
@@ -136,6 +309,34 @@ func (a *Api) Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !utils.ValidEmail(request.Email) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadEmail}}, a.log)
+		return
+	}
+
+	if !utils.ValidBirthday(request.Birthday) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadBirthday}}, a.log)
+		return
+	}
+
+	if request.Timezone == "" {
+		request.Timezone = utils.DefaultTimezone
+	}
+
+	if !utils.ValidTimezone(request.Timezone) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownTimezone}}, a.log)
+		return
+	}
+
+	if request.Locale == "" {
+		request.Locale = utils.DefaultLocale
+	}
+
+	if !utils.ValidLocale(request.Locale) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownLocale}}, a.log)
+		return
+	}
+
 	found, err := a.profile.FindUserByLogin(r.Context(), request.Login)
 	if err != nil {
 		a.log.Error("Signup error: ", err.Error())
@@ -207,94 +408,1632 @@ func (a *Api) AuthAccept(w http.ResponseWriter, r *http.Request) {
 	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: models.AuthCheckResponse{Login: login}}, a.log)
 }
 
+// GetEmployees lists employees. Supported query parameters: q (substring of
+// login or e-mail), month, from and to (MM-DD), sort (name, birthday or
+// upcoming), limit, and either offset or cursor.
 func (a *Api) GetEmployees(w http.ResponseWriter, r *http.Request) {
-	offset, err := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
+	query := r.URL.Query()
+	offset, limit := pageParams(query)
+
+	filter := &models.EmployeeFilter{
+		Query:  query.Get("q"),
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Sort:   query.Get("sort"),
+		Offset: offset,
+		Limit:  limit,
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = models.SortByName
+	}
+
+	if filter.Sort != models.SortByName && filter.Sort != models.SortByBirthday && filter.Sort != models.SortByUpcoming {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadFilter}}, a.log)
+		return
+	}
+
+	var err error
+	if month := query.Get("month"); month != "" {
+		filter.Month, err = strconv.Atoi(month)
+		if err != nil || filter.Month < 1 || filter.Month > 12 {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadFilter}}, a.log)
+			return
+		}
+	}
+
+	if !validMonthDay(filter.From) || !validMonthDay(filter.To) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadFilter}}, a.log)
+		return
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		filter.Cursor = &models.EmployeeCursor{}
+		err = utils.DecodeCursor(cursor, filter.Cursor)
+		if err != nil || filter.Cursor.Sort != filter.Sort {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadCursor}}, a.log)
+			return
+		}
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	page, err := a.profile.GetEmployees(r.Context(), userId, filter)
 	if err != nil {
-		offset = 0
+		a.log.Error("Get employees error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}, a.log)
+		return
 	}
 
-	limit, err := strconv.ParseUint(r.URL.Query().Get("limit"), 10, 64)
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: page}, a.log)
+}
+
+// pageParams reads offset and limit, 8 by default and at most
+// maxEmployeesLimit.
+func pageParams(query url.Values) (uint64, uint64) {
+	offset, err := strconv.ParseUint(query.Get("offset"), 10, 64)
 	if err != nil {
+		offset = 0
+	}
+
+	limit, err := strconv.ParseUint(query.Get("limit"), 10, 64)
+	if err != nil || limit == 0 {
 		limit = 8
 	}
 
-	emps, err := a.profile.GetEmployees(r.Context(), offset, limit)
+	if limit > maxEmployeesLimit {
+		limit = maxEmployeesLimit
+	}
+
+	return offset, limit
+}
+
+// validMonthDay accepts an empty string or a MM-DD day of a leap year.
+func validMonthDay(day string) bool {
+	if day == "" {
+		return true
+	}
+
+	_, err := time.Parse("01-02", day)
+	return err == nil && len(day) == len("01-02")
+}
+
+func (a *Api) GetEmployee(w http.ResponseWriter, r *http.Request) {
+	id, ok := employeeId(r)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+		return
+	}
+
+	a.sendEmployee(w, r, id)
+}
+
+func (a *Api) GetMe(w http.ResponseWriter, r *http.Request) {
+	a.sendEmployee(w, r, r.Context().Value(middleware.UserIDKey).(uint64))
+}
+
+// sendEmployee shows the whole birthday only to the employee and to those
+// who manage employees.
+func (a *Api) sendEmployee(w http.ResponseWriter, r *http.Request, id uint64) {
+	employee, found, err := a.profile.GetEmployee(r.Context(), id)
 	if err != nil {
-		a.log.Error("Get employees error: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}, a.log)
+		a.log.Error("Get employee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	if !found {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
 		return
 	}
 
-	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: emps}, a.log)
+	if id != r.Context().Value(middleware.UserIDKey).(uint64) {
+		manager, err := a.canManageEmployees(r)
+		if err != nil {
+			a.log.Error("Get employee error: ", err.Error())
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+			return
+		}
+
+		if !manager {
+			employee.HideBirthday()
+		}
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: employee}, a.log)
 }
 
-func (a *Api) BirthdaySub(w http.ResponseWriter, r *http.Request) {
-	var request models.SubRequest
+// UpdateEmployee changes the profile of another employee, which requires the
+// permission to manage employees. Employees may change their own profile
+// except for the department.
+func (a *Api) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
+	id, ok := employeeId(r)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+		return
+	}
+
+	a.updateEmployee(w, r, id)
+}
+
+func (a *Api) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	a.updateEmployee(w, r, r.Context().Value(middleware.UserIDKey).(uint64))
+}
+
+func (a *Api) updateEmployee(w http.ResponseWriter, r *http.Request, id uint64) {
+	var request models.ProfileRequest
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		a.log.Error("BirthdaySub error: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bab request"}}, a.log)
+		a.log.Error("UpdateEmployee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
 		return
 	}
 
 	err = json.Unmarshal(body, &request)
 	if err != nil {
 		a.log.Error("Error unmarshalling request: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bab request"}}, a.log)
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
 		return
 	}
 
-	request.UserFromId = r.Context().Value(middleware.UserIDKey).(uint64)
-	res, err := a.sub.BirthdaySub(r.Context(), request.UserFromId, request.UserToId)
+	manager, err := a.canManageEmployees(r)
 	if err != nil {
-		if err.Error() == errs.ErrDuplicateSub.Error() {
-			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusConflict, Body: models.ErrorResponse{Error: "Already exist"}}, a.log)
+		a.log.Error("Update employee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	if !manager && (id != userId || request.Department != nil) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusForbidden, Body: models.ErrorResponse{Error: errs.ErrForbidden}}, a.log)
+		return
+	}
+
+	if id != userId && !a.checkOutranks(w, r, id) {
+		return
+	}
+
+	if request.Email != nil && !utils.ValidEmail(*request.Email) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadEmail}}, a.log)
+		return
+	}
+
+	if request.Birthday != nil && !utils.ValidBirthday(*request.Birthday) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadBirthday}}, a.log)
+		return
+	}
+
+	if request.Timezone != nil && !utils.ValidTimezone(*request.Timezone) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownTimezone}}, a.log)
+		return
+	}
+
+	if request.Locale != nil && !utils.ValidLocale(*request.Locale) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownLocale}}, a.log)
+		return
+	}
+
+	err = a.profile.UpdateEmployee(r.Context(), id, &request)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
 			return
 		}
+		a.log.Error("Update employee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
 
-		a.log.Error("Birthday sub error: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}, a.log)
+	a.sendEmployee(w, r, id)
+}
+
+// DeleteEmployee removes the profile of someone who left. Managers cannot
+// remove their own profile.
+func (a *Api) DeleteEmployee(w http.ResponseWriter, r *http.Request) {
+	id, ok := employeeId(r)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
 		return
 	}
 
-	if !res {
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: "Not found"}}, a.log)
+	manager, err := a.canManageEmployees(r)
+	if err != nil {
+		a.log.Error("Delete employee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	if !manager {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusForbidden, Body: models.ErrorResponse{Error: errs.ErrForbidden}}, a.log)
+		return
+	}
+
+	if id == r.Context().Value(middleware.UserIDKey).(uint64) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	if !a.checkOutranks(w, r, id) {
+		return
+	}
+
+	err = a.profile.DeleteEmployee(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+			return
+		}
+		a.log.Error("Delete employee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
 		return
 	}
 
 	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
 }
 
-func (a *Api) BirthdayUnSub(w http.ResponseWriter, r *http.Request) {
-	var request models.UnSubRequest
+// ImportEmployees creates employees from a CSV file with a header row or
+// from JSON lines, both with login, email, birthday and an optional
+// department. With dry_run=true only the validation is done. Any invalid row
+// fails the whole import.
+func (a *Api) ImportEmployees(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		a.log.Error("BirthdayUnSub error: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bad request"}}, a.log)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var rows []*models.ImportRow
+	var err error
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	switch mediaType {
+	case "text/csv":
+		rows, err = readCSVRows(body)
+	case "application/x-ndjson", "application/jsonl", "application/json":
+		rows, err = readJSONRows(body)
+	default:
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusUnsupportedMediaType, Body: models.ErrorResponse{Error: errs.ErrBadImport}}, a.log)
 		return
 	}
 
-	err = json.Unmarshal(body, &request)
 	if err != nil {
-		a.log.Error("Error unmarshalling request: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bad request"}}, a.log)
+		a.log.Error("ImportEmployees error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: err.Error()}}, a.log)
 		return
 	}
 
-	request.UserFromId = r.Context().Value(middleware.UserIDKey).(uint64)
-	_, err = a.sub.BirthdayUnSub(r.Context(), request.UserFromId, request.UserToId)
+	if len(rows) == 0 || len(rows) > maxImportRows {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	result, err := a.profile.ImportEmployees(r.Context(), rows, dryRun)
 	if err != nil {
-		if err.Error() == errs.ErrNotFound.Error() {
-			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: "Not found"}}, a.log)
+		if errors.Is(err, errs.ErrDuplicate) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusConflict, Body: models.ErrorResponse{Error: errs.ErrAlreadyExists}}, a.log)
 			return
 		}
+		a.log.Error("Import employees error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
 
-		a.log.Error("Birthday sub error: ", err.Error())
-		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}, a.log)
+	status := http.StatusOK
+	if len(result.Errors) != 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: status, Body: result}, a.log)
+}
+
+// readCSVRows reads a CSV file whose first row names the columns.
+func readCSVRows(body io.Reader) ([]*models.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header error: %s", err.Error())
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// spreadsheet programs often start the file with a byte order mark
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]*models.ImportRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv error: %s", err.Error())
+		}
+
+		rows = append(rows, &models.ImportRow{
+			Row:        len(rows) + 1,
+			Login:      field(record, "login"),
+			Email:      field(record, "email"),
+			Birthday:   field(record, "birthday"),
+			Department: field(record, "department"),
+		})
+	}
+
+	return rows, nil
+}
+
+// readJSONRows reads one JSON object per line. A line that is not valid JSON
+// becomes a row without fields, so it is reported with the other rows.
+func readJSONRows(body io.Reader) ([]*models.ImportRow, error) {
+	rows := make([]*models.ImportRow, 0)
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row := &models.ImportRow{}
+		if err := json.Unmarshal(line, row); err != nil {
+			row = &models.ImportRow{}
+		}
+		row.Row = len(rows) + 1
+		row.Login = strings.TrimSpace(row.Login)
+		row.Email = strings.TrimSpace(row.Email)
+		row.Birthday = strings.TrimSpace(row.Birthday)
+		row.Department = strings.TrimSpace(row.Department)
+
+		rows = append(rows, row)
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("read json lines error: %s", err.Error())
+	}
+
+	return rows, nil
+}
+
+func (a *Api) ExportEmployees(w http.ResponseWriter, r *http.Request) {
+	a.sendExport(w, r, "employees", a.export.ExportEmployees)
+}
+
+func (a *Api) ExportSubscriptions(w http.ResponseWriter, r *http.Request) {
+	a.sendExport(w, r, "subscriptions", a.export.ExportSubscriptions)
+}
+
+// sendExport streams the export in the format from the query, csv by
+// default. Once rows are sent an error can only be logged, so the client
+// gets a truncated file.
+func (a *Api) sendExport(w http.ResponseWriter, r *http.Request, name string, write func(ctx context.Context, format string, out io.Writer) error) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+
+	if !export.ValidFormat(format) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadExportFormat}}, a.log)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format(time.DateOnly), format)
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	err := write(r.Context(), format, w)
+	if err != nil {
+		a.log.Error("Export ", name, " error: ", err.Error())
+	}
+}
+
+func (a *Api) SetPassword(w http.ResponseWriter, r *http.Request) {
+	var request models.PasswordSetRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("SetPassword error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil || request.Token == "" || request.Password == "" {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	found, err := a.profile.SetPasswordByToken(r.Context(), request.Token, request.Password)
+	if err != nil {
+		a.log.Error("Set password error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	if !found {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrBadToken}}, a.log)
 		return
 	}
 
 	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
 }
+
+// employeeId parses the id from /api/v1/employees/{id}.
+func employeeId(r *http.Request) (uint64, bool) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/api/v1/employees/"), 10, 64)
+	return id, err == nil
+}
+
+func (a *Api) canManageEmployees(r *http.Request) (bool, error) {
+	role, err := a.profile.GetUserRole(r.Context(), r.Context().Value(middleware.UserIDKey).(uint64))
+	if err != nil {
+		return false, err
+	}
+
+	return models.HasPermission(role, models.PermManageEmployees), nil
+}
+
+// checkOutranks tells whether the caller may act on the employee id and
+// sends the error response when not. Employees who may manage roles can only
+// be changed by someone who may manage roles too, so HR cannot act on an
+// admin.
+func (a *Api) checkOutranks(w http.ResponseWriter, r *http.Request, id uint64) bool {
+	target, found, err := a.profile.GetEmployee(r.Context(), id)
+	if err != nil {
+		a.log.Error("Get employee error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return false
+	}
+
+	if !found {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+		return false
+	}
+
+	if !models.HasPermission(target.Role, models.PermManageRoles) {
+		return true
+	}
+
+	role, err := a.profile.GetUserRole(r.Context(), r.Context().Value(middleware.UserIDKey).(uint64))
+	if err != nil {
+		a.log.Error("Get user role error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return false
+	}
+
+	if !models.HasPermission(role, models.PermManageRoles) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusForbidden, Body: models.ErrorResponse{Error: errs.ErrForbidden}}, a.log)
+		return false
+	}
+
+	return true
+}
+
+func (a *Api) GetUpcomingBirthdays(w http.ResponseWriter, r *http.Request) {
+	days := defaultUpcomingDays
+
+	if param := r.URL.Query().Get("days"); param != "" {
+		var err error
+		days, err = strconv.Atoi(param)
+		if err != nil || days < 0 || days > maxUpcomingDays {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadDays}}, a.log)
+			return
+		}
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	birthdays, err := a.birthday.GetUpcomingBirthdays(r.Context(), userId, days)
+	if err != nil {
+		a.log.Error("Get upcoming birthdays error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: birthdays}, a.log)
+}
+
+func (a *Api) GetBirthdayCalendar(w http.ResponseWriter, r *http.Request) {
+	month := time.Now()
+
+	if param := r.URL.Query().Get("month"); param != "" {
+		var err error
+		month, err = time.Parse("2006-01", param)
+		if err != nil {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadMonth}}, a.log)
+			return
+		}
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	birthdays, err := a.birthday.GetBirthdayCalendar(r.Context(), userId, month.Year(), month.Month())
+	if err != nil {
+		a.log.Error("Get birthday calendar error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: birthdays}, a.log)
+}
+
+// GetFeed serves the iCalendar feed. Calendar clients cannot send the
+// session cookie, so the feed is authorized by the token query parameter.
+// Entries drop out of the feed without a newer change time to report, so
+// there is no Last-Modified and http.ServeContent answers 304 only to a
+// matching If-None-Match.
+func (a *Api) GetFeed(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusUnauthorized, Body: models.ErrorResponse{Error: errs.ErrUnauthorized}}, a.log)
+		return
+	}
+
+	feed, found, err := a.feed.GetFeed(r.Context(), token)
+	if err != nil {
+		a.log.Error("Get feed error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	if !found {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusUnauthorized, Body: models.ErrorResponse{Error: errs.ErrUnauthorized}}, a.log)
+		return
+	}
+
+	sum := sha256.Sum256(feed)
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "private, max-age=0, must-revalidate")
+	http.ServeContent(w, r, "feed.ics", time.Time{}, bytes.NewReader(feed))
+}
+
+// CreateFeedToken issues a new feed token, revoking the previous one, and
+// returns the URL to give to a calendar client.
+func (a *Api) CreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+
+	token, err := a.feed.CreateFeedToken(r.Context(), userId)
+	if err != nil {
+		a.log.Error("Create feed token error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	feedUrl := url.URL{Scheme: scheme, Host: r.Host, Path: feedPath, RawQuery: url.Values{"token": {token}}.Encode()}
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: models.FeedTokenResponse{Token: token, Url: feedUrl.String()}}, a.log)
+}
+
+func (a *Api) RevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+
+	deleted, err := a.feed.RevokeFeedToken(r.Context(), userId)
+	if err != nil {
+		a.log.Error("Revoke feed token error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	if !deleted {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) BirthdaySub(w http.ResponseWriter, r *http.Request) {
+	var request models.SubRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("BirthdaySub error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bab request"}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bab request"}}, a.log)
+		return
+	}
+
+	reminders, ok := utils.NormalizeReminders(request.Reminders)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadReminders}}, a.log)
+		return
+	}
+
+	request.UserFromId = r.Context().Value(middleware.UserIDKey).(uint64)
+	res, err := a.sub.BirthdaySub(r.Context(), request.UserFromId, request.UserToId, reminders)
+	if err != nil {
+		if err.Error() == errs.ErrDuplicateSub.Error() {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusConflict, Body: models.ErrorResponse{Error: "Already exist"}}, a.log)
+			return
+		}
+
+		if errors.Is(err, errs.ErrSubscriptionRefused) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusForbidden, Body: models.ErrorResponse{Error: errs.ErrRefusedSubscription}}, a.log)
+			return
+		}
+
+		a.log.Error("Birthday sub error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}, a.log)
+		return
+	}
+
+	if !res {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: "Not found"}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+// UpdateReminders replaces the reminders of an existing subscription.
+func (a *Api) UpdateReminders(w http.ResponseWriter, r *http.Request) {
+	var request models.SubRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("UpdateReminders error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	reminders, ok := utils.NormalizeReminders(request.Reminders)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadReminders}}, a.log)
+		return
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	err = a.sub.UpdateReminders(r.Context(), userId, request.UserToId, reminders)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+			return
+		}
+		a.log.Error("Update reminders error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) BirthdayUnSub(w http.ResponseWriter, r *http.Request) {
+	var request models.UnSubRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("BirthdayUnSub error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bad request"}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: "Bad request"}}, a.log)
+		return
+	}
+
+	request.UserFromId = r.Context().Value(middleware.UserIDKey).(uint64)
+	_, err = a.sub.BirthdayUnSub(r.Context(), request.UserFromId, request.UserToId)
+	if err != nil {
+		if err.Error() == errs.ErrNotFound.Error() {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: "Not found"}}, a.log)
+			return
+		}
+
+		a.log.Error("Birthday sub error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: "Internal server error"}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+// GetSubscriptions lists the employees the user gets notified about.
+func (a *Api) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	offset, limit := pageParams(r.URL.Query())
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+
+	page, err := a.sub.GetSubscriptions(r.Context(), userId, offset, limit)
+	if err != nil {
+		a.log.Error("Get subscriptions error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: page}, a.log)
+}
+
+// GetSubscribers lists the employees notified about the user, or about the
+// employee from the user_id query parameter unless they hide it.
+func (a *Api) GetSubscribers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, limit := pageParams(query)
+	viewerId := r.Context().Value(middleware.UserIDKey).(uint64)
+
+	userId := viewerId
+	if param := query.Get("user_id"); param != "" {
+		var err error
+		userId, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+			return
+		}
+	}
+
+	page, err := a.sub.GetSubscribers(r.Context(), viewerId, userId, offset, limit)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+			return
+		}
+		if errors.Is(err, errs.ErrHidden) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusForbidden, Body: models.ErrorResponse{Error: errs.ErrForbidden}}, a.log)
+			return
+		}
+		a.log.Error("Get subscribers error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: page}, a.log)
+}
+
+// GetTeams lists all teams with the number of members and whether the user
+// is subscribed to each of them.
+func (a *Api) GetTeams(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+
+	teams, err := a.team.GetTeams(r.Context(), userId)
+	if err != nil {
+		a.log.Error("Get teams error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: teams}, a.log)
+}
+
+func (a *Api) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeam(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := a.team.CreateTeam(r.Context(), request.Name)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: models.TeamRequest{Id: id, Name: request.Name}}, a.log)
+}
+
+func (a *Api) UpdateTeam(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeam(w, r)
+	if !ok {
+		return
+	}
+
+	err := a.team.UpdateTeam(r.Context(), request)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	var request models.TeamRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("DeleteTeam error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = a.team.DeleteTeam(r.Context(), request.Id)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+// GetTeamMembers lists the members of the team from the team_id query
+// parameter.
+func (a *Api) GetTeamMembers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, limit := pageParams(query)
+
+	teamId, err := strconv.ParseUint(query.Get("team_id"), 10, 64)
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	page, err := a.team.GetTeamMembers(r.Context(), teamId, offset, limit)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: page}, a.log)
+}
+
+func (a *Api) AddTeamMember(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeamMember(w, r)
+	if !ok {
+		return
+	}
+
+	err := a.team.AddTeamMember(r.Context(), request.TeamId, request.UserId)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeamMember(w, r)
+	if !ok {
+		return
+	}
+
+	err := a.team.RemoveTeamMember(r.Context(), request.TeamId, request.UserId)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) TeamSub(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeamSub(w, r)
+	if !ok {
+		return
+	}
+
+	reminders, ok := utils.NormalizeReminders(request.Reminders)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadReminders}}, a.log)
+		return
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	err := a.team.TeamSub(r.Context(), request.TeamId, userId, reminders)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) UpdateTeamReminders(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeamSub(w, r)
+	if !ok {
+		return
+	}
+
+	reminders, ok := utils.NormalizeReminders(request.Reminders)
+	if !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadReminders}}, a.log)
+		return
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	err := a.team.UpdateTeamReminders(r.Context(), request.TeamId, userId, reminders)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) TeamUnSub(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTeamSub(w, r)
+	if !ok {
+		return
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	err := a.team.TeamUnSub(r.Context(), request.TeamId, userId)
+	if err != nil {
+		a.sendTeamError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) readTeam(w http.ResponseWriter, r *http.Request) (*models.TeamRequest, bool) {
+	var request models.TeamRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("Team error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadTeamName}}, a.log)
+		return nil, false
+	}
+
+	return &request, true
+}
+
+func (a *Api) readTeamMember(w http.ResponseWriter, r *http.Request) (*models.TeamMemberRequest, bool) {
+	var request models.TeamMemberRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("Team member error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	return &request, true
+}
+
+func (a *Api) readTeamSub(w http.ResponseWriter, r *http.Request) (*models.TeamSubRequest, bool) {
+	var request models.TeamSubRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("Team subscription error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	return &request, true
+}
+
+func (a *Api) sendTeamError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+	case errors.Is(err, errs.ErrDuplicate):
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusConflict, Body: models.ErrorResponse{Error: errs.ErrAlreadyExists}}, a.log)
+	default:
+		a.log.Error("Team error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+	}
+}
+
+func (a *Api) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var request models.SettingsRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("UpdateSettings error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	if request.Timezone != nil && !utils.ValidTimezone(*request.Timezone) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownTimezone}}, a.log)
+		return
+	}
+
+	if request.Locale != nil && !utils.ValidLocale(*request.Locale) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownLocale}}, a.log)
+		return
+	}
+
+	if request.DeliveryMode != nil && !slices.Contains(models.DeliveryModes, *request.DeliveryMode) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownDeliveryMode}}, a.log)
+		return
+	}
+
+	if request.Visibility != nil && !slices.Contains(models.Visibilities, *request.Visibility) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownVisibility}}, a.log)
+		return
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	err = a.profile.UpdateSettings(r.Context(), userId, &request)
+	if err != nil {
+		a.log.Error("Update settings error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) GetChannels(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+
+	channels, err := a.profile.GetChannels(r.Context(), userId)
+	if err != nil {
+		a.log.Error("Get channels error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: channels}, a.log)
+}
+
+func (a *Api) SetChannels(w http.ResponseWriter, r *http.Request) {
+	var request models.ChannelsRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("SetChannels error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, channel := range request.Channels {
+		if channel == nil || seen[channel.Channel] || !validChannel(channel) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadChannel}}, a.log)
+			return
+		}
+		seen[channel.Channel] = true
+	}
+
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	err = a.profile.SetChannels(r.Context(), userId, request.Channels)
+	if err != nil {
+		a.log.Error("Set channels error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+// validChannel checks that the address fits the channel. An empty e-mail
+// address means the one from the profile. Webhook and Slack URLs must use
+// https and may not name a local or private host; the worker checks the
+// resolved address again before connecting.
+func validChannel(channel *models.Channel) bool {
+	switch channel.Channel {
+	case models.ChannelEmail:
+		if channel.Address == "" {
+			return true
+		}
+		_, err := mail.ParseAddress(channel.Address)
+		return err == nil
+	case models.ChannelWebhook, models.ChannelSlack:
+		u, err := url.Parse(channel.Address)
+		return err == nil && u.Scheme == "https" && u.Hostname() != "" && !localHost(u.Hostname())
+	case models.ChannelTelegram:
+		return channel.Address != ""
+	}
+
+	return false
+}
+
+// localHost reports whether host is localhost or an IP address that is not
+// public.
+func localHost(host string) bool {
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+	return !addr.IsGlobalUnicast() || addr.IsPrivate()
+}
+
+func (a *Api) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := a.template.GetTemplates(r.Context())
+	if err != nil {
+		a.log.Error("Get templates error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: templates}, a.log)
+}
+
+func (a *Api) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := a.template.CreateTemplate(r.Context(), request)
+	if err != nil {
+		a.sendTemplateError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: models.TemplateRequest{Id: id}}, a.log)
+}
+
+func (a *Api) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	request, ok := a.readTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := a.template.UpdateTemplate(r.Context(), request)
+	if err != nil {
+		a.sendTemplateError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	var request models.TemplateRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("DeleteTemplate error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = a.template.DeleteTemplate(r.Context(), request.Id)
+	if err != nil {
+		a.sendTemplateError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	var request models.PreviewRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("PreviewTemplate error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	rendered, err := a.template.PreviewTemplate(r.Context(), &request)
+	if err != nil {
+		a.sendTemplateError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: rendered}, a.log)
+}
+
+// UploadTemplateCard takes the raw image as the request body and the template
+// id as the id query parameter.
+func (a *Api) UploadTemplateCard(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCardSize))
+	if err != nil {
+		a.log.Error("UploadTemplateCard error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadCard}}, a.log)
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(cardContentTypes, contentType) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadCard}}, a.log)
+		return
+	}
+
+	err = a.template.SetTemplateCard(r.Context(), id, &models.Card{ContentType: contentType, Data: data})
+	if err != nil {
+		a.sendTemplateError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) DeleteTemplateCard(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = a.template.SetTemplateCard(r.Context(), id, nil)
+	if err != nil {
+		a.sendTemplateError(w, r, err)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) readTemplate(w http.ResponseWriter, r *http.Request) (*models.Template, bool) {
+	var request models.Template
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("Template error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return nil, false
+	}
+
+	if !slices.Contains(models.TemplateNames, request.Name) || !utils.ValidLocale(request.Locale) {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadTemplate}}, a.log)
+		return nil, false
+	}
+
+	return &request, true
+}
+
+func (a *Api) sendTemplateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errs.ErrTemplate):
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: err.Error()}}, a.log)
+	case errors.Is(err, errs.ErrNotFound):
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+	case errors.Is(err, errs.ErrDuplicate):
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusConflict, Body: models.ErrorResponse{Error: errs.ErrAlreadyExists}}, a.log)
+	default:
+		a.log.Error("Template error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+	}
+}
+
+func (a *Api) GetSessions(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusUnauthorized, Body: models.ErrorResponse{Error: "Not authorized"}}, a.log)
+		return
+	}
+
+	sessions, err := a.session.GetSessions(r.Context(), cookie.Value)
+	if err != nil {
+		a.log.Error("Get sessions error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: sessions}, a.log)
+}
+
+func (a *Api) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	var request models.SessionRequest
+
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusUnauthorized, Body: models.ErrorResponse{Error: "Not authorized"}}, a.log)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("RevokeSession error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = a.session.RevokeSession(r.Context(), cookie.Value, request.Id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+			return
+		}
+
+		a.log.Error("Revoke session error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusUnauthorized, Body: models.ErrorResponse{Error: "Not authorized"}}, a.log)
+		return
+	}
+
+	err = a.session.RevokeAllSessions(r.Context(), cookie.Value)
+	if err != nil {
+		a.log.Error("Revoke sessions error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	cookie.Expires = time.Now().AddDate(0, 0, -1)
+	http.SetCookie(w, cookie)
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) SetRole(w http.ResponseWriter, r *http.Request) {
+	var request models.RoleRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("SetRole error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	if _, ok := models.RolePermissions[request.Role]; !ok {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrUnknownRole}}, a.log)
+		return
+	}
+
+	// admins cannot change their own role, so at least one admin always remains
+	userId := r.Context().Value(middleware.UserIDKey).(uint64)
+	if request.UserId == userId {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = a.profile.SetUserRole(r.Context(), request.UserId, request.Role)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+			return
+		}
+		a.log.Error("Set role error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) GetWorkerState(w http.ResponseWriter, r *http.Request) {
+	workerState, err := a.worker.GetWorkerState(r.Context())
+	if err != nil {
+		a.log.Error("Get worker state error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: workerState}, a.log)
+}
+
+func (a *Api) SetWorkerSchedule(w http.ResponseWriter, r *http.Request) {
+	var request models.ScheduleRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("SetWorkerSchedule error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		a.log.Error("Error unmarshalling request: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	err = a.worker.SetWorkerSchedule(r.Context(), strings.TrimSpace(request.Schedule))
+	if err != nil {
+		if errors.Is(err, errs.ErrSchedule) {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: err.Error()}}, a.log)
+			return
+		}
+		a.log.Error("Set worker schedule error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+func (a *Api) PauseWorker(w http.ResponseWriter, r *http.Request) {
+	a.setWorkerPaused(w, r, true)
+}
+
+func (a *Api) ResumeWorker(w http.ResponseWriter, r *http.Request) {
+	a.setWorkerPaused(w, r, false)
+}
+
+func (a *Api) setWorkerPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	err := a.worker.SetWorkerPaused(r.Context(), paused)
+	if err != nil {
+		a.log.Error("Set worker paused error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: nil}, a.log)
+}
+
+// TriggerWorker records a run of the birthday job for the requested date,
+// today by default, for the worker to do. A dry run only lists the messages
+// that would be queued. The response is the recorded run, whose state is
+// returned by GetWorkerTrigger.
+func (a *Api) TriggerWorker(w http.ResponseWriter, r *http.Request) {
+	var request models.TriggerRequest
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		a.log.Error("TriggerWorker error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			a.log.Error("Error unmarshalling request: ", err.Error())
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+			return
+		}
+	}
+
+	date := time.Now()
+	if request.Date != "" {
+		date, err = time.Parse(time.DateOnly, request.Date)
+		if err != nil {
+			httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadDate}}, a.log)
+			return
+		}
+	}
+
+	trigger, err := a.worker.TriggerWorker(r.Context(), date, request.DryRun)
+	if err != nil {
+		a.log.Error("Trigger worker error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusAccepted, Body: trigger}, a.log)
+}
+
+// GetWorkerTrigger returns the run recorded by TriggerWorker with the id
+// query parameter.
+func (a *Api) GetWorkerTrigger(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusBadRequest, Body: models.ErrorResponse{Error: errs.ErrBadRequest}}, a.log)
+		return
+	}
+
+	trigger, found, err := a.worker.GetWorkerTrigger(r.Context(), id)
+	if err != nil {
+		a.log.Error("Get worker trigger error: ", err.Error())
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusInternalServerError, Body: models.ErrorResponse{Error: errs.ErrInternalServer}}, a.log)
+		return
+	}
+
+	if !found {
+		httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusNotFound, Body: models.ErrorResponse{Error: errs.ErrNotFoundString}}, a.log)
+		return
+	}
+
+	httpResponse.SendResponse(w, r, &models.Response{Status: http.StatusOK, Body: trigger}, a.log)
+}
+
+// clientIP prefers the address set by nginx over the proxy's own address.
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,39 @@
+package delivery
+
+import (
+	"testing"
+	"vk-rest/pkg/models"
+)
+
+func TestValidChannel(t *testing.T) {
+	tests := []struct {
+		channel string
+		address string
+		valid   bool
+	}{
+		{models.ChannelEmail, "", true},
+		{models.ChannelEmail, "ivan@example.com", true},
+		{models.ChannelEmail, "ivan", false},
+		{models.ChannelWebhook, "https://example.com/hook", true},
+		{models.ChannelSlack, "https://hooks.slack.com/services/T0/B0/X", true},
+		{models.ChannelWebhook, "http://example.com/hook", false},
+		{models.ChannelWebhook, "ftp://example.com/hook", false},
+		{models.ChannelWebhook, "https://localhost/hook", false},
+		{models.ChannelWebhook, "https://api.localhost/hook", false},
+		{models.ChannelWebhook, "https://127.0.0.1/hook", false},
+		{models.ChannelWebhook, "https://169.254.169.254/latest/meta-data", false},
+		{models.ChannelSlack, "https://10.0.0.5/hook", false},
+		{models.ChannelSlack, "https://[::1]/hook", false},
+		{models.ChannelWebhook, "https:///hook", false},
+		{models.ChannelTelegram, "-1001", true},
+		{models.ChannelTelegram, "", false},
+		{"sms", "+70000000000", false},
+	}
+
+	for _, tt := range tests {
+		got := validChannel(&models.Channel{Channel: tt.channel, Address: tt.address})
+		if got != tt.valid {
+			t.Errorf("validChannel(%s, %q) = %t, want %t", tt.channel, tt.address, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"vk-rest/pkg/middleware"
+	"vk-rest/pkg/models"
+	"vk-rest/service/usecase/core"
+)
+
+// fakeProfileCore keeps employees in memory and records the changes.
+type fakeProfileCore struct {
+	usecase.IProfileCore
+	employees map[uint64]*models.UserItem
+	updated   []uint64
+	deleted   []uint64
+}
+
+func (p *fakeProfileCore) GetUserRole(ctx context.Context, userId uint64) (string, error) {
+	return p.employees[userId].Role, nil
+}
+
+func (p *fakeProfileCore) GetEmployee(ctx context.Context, id uint64) (*models.UserItem, bool, error) {
+	employee, found := p.employees[id]
+	return employee, found, nil
+}
+
+func (p *fakeProfileCore) UpdateEmployee(ctx context.Context, id uint64, update *models.ProfileRequest) error {
+	p.updated = append(p.updated, id)
+	return nil
+}
+
+func (p *fakeProfileCore) DeleteEmployee(ctx context.Context, id uint64) error {
+	p.deleted = append(p.deleted, id)
+	return nil
+}
+
+func testEmployeesApi() (*Api, *fakeProfileCore) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	profiles := &fakeProfileCore{employees: map[uint64]*models.UserItem{
+		1: {Id: 1, Login: "admin", Role: models.RoleAdmin},
+		2: {Id: 2, Login: "hr", Role: models.RoleHR},
+		3: {Id: 3, Login: "ivan", Role: models.RoleEmployee},
+		4: {Id: 4, Login: "root", Role: models.RoleAdmin},
+	}}
+
+	return &Api{log: log, profile: profiles}, profiles
+}
+
+func callEmployee(handler http.HandlerFunc, method string, caller, id uint64, body string) int {
+	r := httptest.NewRequest(method, "/api/v1/employees/"+strconv.FormatUint(id, 10), strings.NewReader(body))
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, caller))
+	w := httptest.NewRecorder()
+
+	handler(w, r)
+
+	var response models.Response
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		return 0
+	}
+
+	return response.Status
+}
+
+func TestManageEmployeeRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller uint64
+		target uint64
+		status int
+	}{
+		{"hr on admin", 2, 1, http.StatusForbidden},
+		{"hr on employee", 2, 3, http.StatusOK},
+		{"admin on admin", 1, 4, http.StatusOK},
+		{"admin on hr", 1, 2, http.StatusOK},
+		{"employee on employee", 3, 2, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		api, profiles := testEmployeesApi()
+
+		status := callEmployee(api.UpdateEmployee, http.MethodPatch, tt.caller, tt.target, `{"department": "R&D"}`)
+		if status != tt.status {
+			t.Errorf("%s: update status %d, want %d", tt.name, status, tt.status)
+		}
+
+		status = callEmployee(api.DeleteEmployee, http.MethodDelete, tt.caller, tt.target, "")
+		if status != tt.status {
+			t.Errorf("%s: delete status %d, want %d", tt.name, status, tt.status)
+		}
+
+		if tt.status == http.StatusForbidden && (len(profiles.updated) != 0 || len(profiles.deleted) != 0) {
+			t.Errorf("%s: forbidden call updated %v and deleted %v", tt.name, profiles.updated, profiles.deleted)
+		}
+	}
+}
+
+func TestUpdateOwnProfileAsAdmin(t *testing.T) {
+	api, profiles := testEmployeesApi()
+
+	status := callEmployee(api.UpdateMe, http.MethodPatch, 1, 1, `{"locale": "en"}`)
+	if status != http.StatusOK || len(profiles.updated) != 1 {
+		t.Errorf("admin updating own profile: status %d, updated %v", status, profiles.updated)
+	}
+}
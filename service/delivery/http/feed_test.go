@@ -0,0 +1,100 @@
+package delivery
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"vk-rest/pkg/ical"
+	"vk-rest/pkg/models"
+	"vk-rest/service/usecase/core"
+)
+
+// fakeFeedCore renders the colleagues of one token and, like the feed
+// query, leaves out the hidden birthdays.
+type fakeFeedCore struct {
+	usecase.IFeedCore
+	token      string
+	colleagues []*models.UserItem
+}
+
+func (f *fakeFeedCore) GetFeed(ctx context.Context, token string) ([]byte, bool, error) {
+	if token != f.token {
+		return nil, false, nil
+	}
+
+	events := make([]*ical.Event, 0, len(f.colleagues))
+	for _, colleague := range f.colleagues {
+		if colleague.Visibility == models.VisibilityHidden {
+			continue
+		}
+		events = append(events, &ical.Event{
+			UID:     "birthday-" + colleague.Login + "@vk-rest",
+			Summary: "День рождения: " + colleague.Login,
+			Start:   time.Date(2000, time.June, 15, 0, 0, 0, 0, time.UTC),
+		})
+	}
+
+	return ical.Calendar("Дни рождения", events), true, nil
+}
+
+func getFeed(api *Api, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, feedPath+"?token=secret", nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+
+	api.GetFeed(w, r)
+
+	return w
+}
+
+func TestFeedAfterColleagueHides(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	feed := &fakeFeedCore{token: "secret", colleagues: []*models.UserItem{
+		{Id: 2, Login: "ivan", Visibility: models.VisibilityFull},
+		{Id: 3, Login: "petr", Visibility: models.VisibilityFull},
+	}}
+	api := &Api{log: log, feed: feed}
+
+	first := getFeed(api, nil)
+	if first.Code != http.StatusOK || !strings.Contains(first.Body.String(), "ivan") {
+		t.Fatalf("first request = %d, want 200 with ivan", first.Code)
+	}
+	if first.Header().Get("Last-Modified") != "" {
+		t.Errorf("Last-Modified = %q, want none", first.Header().Get("Last-Modified"))
+	}
+
+	conditional := http.Header{
+		"If-None-Match":     {first.Header().Get("ETag")},
+		"If-Modified-Since": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+	}
+
+	unchanged := getFeed(api, conditional)
+	if unchanged.Code != http.StatusNotModified {
+		t.Errorf("unchanged feed = %d, want 304", unchanged.Code)
+	}
+
+	feed.colleagues[0].Visibility = models.VisibilityHidden
+
+	changed := getFeed(api, conditional)
+	if changed.Code != http.StatusOK {
+		t.Fatalf("feed after ivan hid = %d, want 200", changed.Code)
+	}
+	if strings.Contains(changed.Body.String(), "ivan") || !strings.Contains(changed.Body.String(), "petr") {
+		t.Errorf("feed after ivan hid:\n%s\nwant petr only", changed.Body.String())
+	}
+
+	// If-Modified-Since alone must not hide the change either
+	since := getFeed(api, http.Header{"If-Modified-Since": conditional["If-Modified-Since"]})
+	if since.Code != http.StatusOK {
+		t.Errorf("feed with If-Modified-Since = %d, want 200", since.Code)
+	}
+}
@@ -0,0 +1,92 @@
+package feed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+)
+
+type IFeedRepo interface {
+	SetToken(ctx context.Context, profileId uint64, tokenHash []byte) error
+	DeleteToken(ctx context.Context, profileId uint64) (bool, error)
+	GetTokenOwner(ctx context.Context, tokenHash []byte) (uint64, bool, error)
+	GetEntries(ctx context.Context, profileId uint64) ([]*models.FeedEntry, error)
+}
+
+type FeedRepo struct {
+	db *sql.DB
+}
+
+func GetFeedRepo(db *sql.DB) *FeedRepo {
+	return &FeedRepo{db: db}
+}
+
+// SetToken stores the hash of a new feed token, replacing the previous one.
+func (r *FeedRepo) SetToken(ctx context.Context, profileId uint64, tokenHash []byte) error {
+	_, err := r.db.ExecContext(ctx, pkg.SetFeedToken, profileId, tokenHash)
+	if err != nil {
+		return fmt.Errorf("set feed token error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *FeedRepo) DeleteToken(ctx context.Context, profileId uint64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, pkg.DeleteFeedToken, profileId)
+	if err != nil {
+		return false, fmt.Errorf("delete feed token error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	return rowsAffected != 0, nil
+}
+
+// GetTokenOwner returns the profile the token hash belongs to.
+func (r *FeedRepo) GetTokenOwner(ctx context.Context, tokenHash []byte) (uint64, bool, error) {
+	var profileId uint64
+
+	err := r.db.QueryRowContext(ctx, pkg.GetFeedToken, tokenHash).Scan(&profileId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("get feed token error: %s", err.Error())
+	}
+
+	return profileId, true, nil
+}
+
+func (r *FeedRepo) GetEntries(ctx context.Context, profileId uint64) ([]*models.FeedEntry, error) {
+	entries := make([]*models.FeedEntry, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetFeedEntries, profileId)
+	if err != nil {
+		return nil, fmt.Errorf("get feed entries query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := &models.FeedEntry{}
+
+		err = rows.Scan(&entry.Id, &entry.Login, &entry.Birthday, &entry.Visibility, &entry.ChangedAt)
+		if err != nil {
+			return nil, fmt.Errorf("get feed entries rows scan error: %s", err.Error())
+		}
+
+		entries = append(entries, entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get feed entries rows error: %s", err.Error())
+	}
+
+	return entries, nil
+}
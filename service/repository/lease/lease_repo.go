@@ -0,0 +1,84 @@
+package lease
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"time"
+	"vk-rest/configs"
+)
+
+const leasePrefix = "lease:"
+
+// renewScript extends the lease only while it still belongs to the owner.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes the lease only while it still belongs to the owner,
+// so a holder whose lease has expired cannot release the next holder's one.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+type ILeaseRepo interface {
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, owner string) error
+}
+
+type LeaseRepo struct {
+	DB *redis.Client
+}
+
+func GetLeaseRepo(cfg *configs.DbRedisCfg, log *logrus.Logger) (ILeaseRepo, error) {
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Host,
+		Password: cfg.Password,
+		DB:       cfg.DbNumber,
+	})
+
+	_, err := redisClient.Ping(context.Background()).Result()
+	if err != nil {
+		log.Error("Ping redis error: ", err)
+		return nil, err
+	}
+
+	log.Info("Redis created successful")
+	return &LeaseRepo{DB: redisClient}, nil
+}
+
+// Acquire takes the lease called name for ttl unless someone holds it.
+func (repo *LeaseRepo) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	acquired, err := repo.DB.SetNX(ctx, leasePrefix+name, owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("acquire lease error: %s", err.Error())
+	}
+
+	return acquired, nil
+}
+
+// Renew extends the lease to ttl from now and reports false when the owner
+// has lost it.
+func (repo *LeaseRepo) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	renewed, err := renewScript.Run(ctx, repo.DB, []string{leasePrefix + name}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("renew lease error: %s", err.Error())
+	}
+
+	return renewed == 1, nil
+}
+
+func (repo *LeaseRepo) Release(ctx context.Context, name, owner string) error {
+	err := releaseScript.Run(ctx, repo.DB, []string{leasePrefix + name}, owner).Err()
+	if err != nil {
+		return fmt.Errorf("release lease error: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+)
+
+type IOutboxRepo interface {
+	Enqueue(ctx context.Context, msg *models.OutboxMessage) (bool, error)
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]*models.OutboxMessage, error)
+	MarkSent(ctx context.Context, id uint64, at time.Time) error
+	MarkFailed(ctx context.Context, id uint64, at time.Time, sendErr string, nextAttempt time.Time, final bool) error
+}
+
+type OutboxRepo struct {
+	db *sql.DB
+}
+
+func GetOutboxRepo(db *sql.DB) *OutboxRepo {
+	return &OutboxRepo{db: db}
+}
+
+// Enqueue stores msg unless a message with the same key already exists or
+// msg is fenced with a stale token. It reports whether a new row was written.
+func (r *OutboxRepo) Enqueue(ctx context.Context, msg *models.OutboxMessage) (bool, error) {
+	res, err := r.db.ExecContext(ctx, pkg.EnqueueOutbox, msg.RecipientId, msg.BirthdayId, msg.Year, msg.Kind, msg.Channel, msg.To, msg.Subject, msg.Body, msg.HTML, msg.CardId, msg.Fence)
+	if err != nil {
+		return false, fmt.Errorf("enqueue outbox error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ClaimPending picks up to limit due messages and hides them from other
+// dispatchers for lease. Messages that are not marked before the lease ends
+// become due again.
+func (r *OutboxRepo) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit uint64) ([]*models.OutboxMessage, error) {
+	messages := make([]*models.OutboxMessage, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.ClaimOutbox, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		msg := &models.OutboxMessage{}
+
+		err := rows.Scan(&msg.Id, &msg.RecipientId, &msg.BirthdayId, &msg.Year, &msg.Kind, &msg.Channel, &msg.To, &msg.Subject, &msg.Body, &msg.HTML, &msg.CardId, &msg.Attempts)
+		if err != nil {
+			return nil, fmt.Errorf("claim outbox rows scan error: %s", err.Error())
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+func (r *OutboxRepo) MarkSent(ctx context.Context, id uint64, at time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx error: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, pkg.MarkOutboxSent, id, at)
+	if err != nil {
+		return fmt.Errorf("mark outbox sent error: %s", err.Error())
+	}
+
+	_, err = tx.ExecContext(ctx, pkg.AddOutboxAttempt, id, at, true, "")
+	if err != nil {
+		return fmt.Errorf("add outbox attempt error: %s", err.Error())
+	}
+
+	return tx.Commit()
+}
+
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id uint64, at time.Time, sendErr string, nextAttempt time.Time, final bool) error {
+	status := models.OutboxStatusPending
+	if final {
+		status = models.OutboxStatusFailed
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx error: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, pkg.MarkOutboxFailed, id, status, nextAttempt, sendErr)
+	if err != nil {
+		return fmt.Errorf("mark outbox failed error: %s", err.Error())
+	}
+
+	_, err = tx.ExecContext(ctx, pkg.AddOutboxAttempt, id, at, false, sendErr)
+	if err != nil {
+		return fmt.Errorf("add outbox attempt error: %s", err.Error())
+	}
+
+	return tx.Commit()
+}
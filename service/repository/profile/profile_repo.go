@@ -5,88 +5,83 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	_ "github.com/jackc/pgx/stdlib"
-	"github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
 	"time"
-	"vk-rest/configs"
 	utils "vk-rest/pkg"
+	errs "vk-rest/pkg/errors"
 	"vk-rest/pkg/models"
 	pkg "vk-rest/pkg/sql"
 )
 
 type IProfileRepo interface {
-	GetUser(ctx context.Context, login string, password []byte) (*models.UserItem, bool, error)
+	GetUser(ctx context.Context, login string) (*models.UserItem, []byte, bool, error)
+	UpdatePassword(ctx context.Context, id uint64, password []byte) error
 	FindUser(ctx context.Context, login string) (bool, error)
 	CreateUser(ctx context.Context, user *models.SignupRequest, password []byte) error
+	ImportUsers(ctx context.Context, rows []*models.ImportRow, tokenHashes [][]byte, expiresAt time.Time) ([]uint64, error)
+	UsePasswordToken(ctx context.Context, tokenHash []byte, password []byte) (bool, error)
 	GetUserId(ctx context.Context, login string) (uint64, error)
-	GetEmployees(ctx context.Context, offset, limit uint64) ([]*models.UserItem, error)
-	GetBirthdayEmployees(ctx context.Context) ([]*models.UserItem, error)
-	GetEmployeeByBirthday(ctx context.Context, id uint64) ([]*models.UserItem, error)
+	GetUserRole(ctx context.Context, id uint64) (string, error)
+	UpdateRole(ctx context.Context, id uint64, role string) error
+	SearchEmployees(ctx context.Context, filter *models.EmployeeFilter) (*models.EmployeesPage, *models.EmployeeCursor, error)
+	GetEmployee(ctx context.Context, id uint64) (*models.UserItem, bool, error)
+	UpdateEmployee(ctx context.Context, id uint64, update *models.ProfileRequest) error
+	DeleteEmployee(ctx context.Context, id uint64) error
+	GetBirthdaysBetween(ctx context.Context, subscriberId uint64, from, to string) ([]*models.UpcomingBirthday, error)
+	GetBirthdayEmployees(ctx context.Context, timezone string, days []string) ([]*models.UserItem, error)
+	GetEmployeeByBirthday(ctx context.Context, id uint64, days int) ([]*models.UserItem, error)
+	GetReminderDays(ctx context.Context) ([]int, error)
+	GetTimezones(ctx context.Context) ([]string, error)
+	UpdateTimezone(ctx context.Context, id uint64, timezone string) error
+	UpdateLocale(ctx context.Context, id uint64, locale string) error
+	UpdateHideSubscribers(ctx context.Context, id uint64, hide bool) error
+	UpdateDeliveryMode(ctx context.Context, id uint64, mode string) error
+	UpdateBirthdayVisibility(ctx context.Context, id uint64, visibility string) error
+	UpdateAllowSubscribe(ctx context.Context, id uint64, allow bool) error
+	GetPrivacy(ctx context.Context, id uint64) (string, bool, bool, error)
+	GetDigestRecipients(ctx context.Context, timezone string, modes []string) ([]*models.DigestRecipient, error)
+	GetSubscribedEmployees(ctx context.Context, id uint64) ([]*models.UserItem, error)
+	GetHideSubscribers(ctx context.Context, id uint64) (bool, bool, error)
+	StreamEmployees(ctx context.Context, fn func(*models.UserItem) error) error
+	StreamSubscriptions(ctx context.Context, fn func(*models.Subscription) error) error
+	GetChannels(ctx context.Context, id uint64) ([]*models.Channel, error)
+	SetChannels(ctx context.Context, id uint64, channels []*models.Channel) error
 }
 
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type ProfileRepo struct {
 	db *sql.DB
 }
 
-func GetPsxRepo(config *configs.DbPsxConfig, log *logrus.Logger) (*ProfileRepo, error) {
-	fmt.Println(config)
-
-	dsn := fmt.Sprintf("user=%s dbname=%s password= %s host=%s port=%d sslmode=%s",
-		config.User, config.Dbname, config.Password, config.Host, config.Port, config.Sslmode)
-	db, err := sql.Open("pgx", dsn)
-	if err != nil {
-		log.Errorf("sql open error: %s", err.Error())
-		return nil, fmt.Errorf("get user r err: %s", err.Error())
-	}
-
-	r := &ProfileRepo{db: db}
-
-	errs := make(chan error)
-	go func() {
-		errs <- r.pingDb(3, log)
-	}()
-
-	if err := <-errs; err != nil {
-		log.Error(err.Error())
-		return nil, err
-	}
-
-	db.SetMaxOpenConns(config.MaxOpenConns)
-	log.Info("Successfully connected to database")
-
-	return r, nil
+func GetPsxRepo(db *sql.DB) *ProfileRepo {
+	return &ProfileRepo{db: db}
 }
 
-func (r *ProfileRepo) pingDb(timer uint32, log *logrus.Logger) error {
-	var err error
-	var retries int
+// GetUser returns the profile together with its stored password hash.
+func (r *ProfileRepo) GetUser(ctx context.Context, login string) (*models.UserItem, []byte, bool, error) {
+	post := &models.UserItem{}
+	var password []byte
 
-	for retries < utils.MaxRetries {
-		err = r.db.Ping()
-		if err == nil {
-			return nil
+	err := r.db.QueryRowContext(ctx, pkg.GetUser, login).Scan(&post.Id, &post.Login, &post.Email, &post.Birthday, &post.Timezone, &post.Locale, &post.Department, &password)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, false, nil
 		}
-
-		retries++
-		log.Errorf("sql ping error: %s", err.Error())
-		time.Sleep(time.Duration(timer) * time.Second)
+		return nil, nil, false, fmt.Errorf("get query user error: %s", err.Error())
 	}
 
-	return fmt.Errorf("sql max pinging error: %s", err.Error())
+	return post, password, true, nil
 }
 
-func (r *ProfileRepo) GetUser(ctx context.Context, login string, password []byte) (*models.UserItem, bool, error) {
-	post := &models.UserItem{}
-
-	err := r.db.QueryRowContext(ctx, pkg.GetUser, login, password).Scan(&post.Id, &post.Login, &post.Email, &post.Birthday)
+func (r *ProfileRepo) UpdatePassword(ctx context.Context, id uint64, password []byte) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdatePassword, id, password)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, false, nil
-		}
-		return nil, false, fmt.Errorf("get query user error: %s", err.Error())
+		return fmt.Errorf("update password error: %s", err.Error())
 	}
 
-	return post, true, nil
+	return nil
 }
 
 func (r *ProfileRepo) FindUser(ctx context.Context, login string) (bool, error) {
@@ -105,7 +100,7 @@ func (r *ProfileRepo) FindUser(ctx context.Context, login string) (bool, error)
 
 func (r *ProfileRepo) CreateUser(ctx context.Context, user *models.SignupRequest, password []byte) error {
 	var userID uint64
-	err := r.db.QueryRowContext(ctx, pkg.CreateUser, user.Login, password, user.Email, user.Birthday).Scan(&userID)
+	err := r.db.QueryRowContext(ctx, pkg.CreateUser, user.Login, password, user.Email, user.Birthday, user.Timezone, user.Locale).Scan(&userID)
 	if err != nil {
 		return fmt.Errorf("create user error: %s", err.Error())
 	}
@@ -113,6 +108,77 @@ func (r *ProfileRepo) CreateUser(ctx context.Context, user *models.SignupRequest
 	return nil
 }
 
+// ImportUsers creates profiles without a password in one transaction, each
+// with a password-set token. A login that is already taken fails the whole
+// import with errs.ErrDuplicate.
+func (r *ProfileRepo) ImportUsers(ctx context.Context, rows []*models.ImportRow, tokenHashes [][]byte, expiresAt time.Time) ([]uint64, error) {
+	ids := make([]uint64, 0, len(rows))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx error: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	for i, row := range rows {
+		var id uint64
+
+		err = tx.QueryRowContext(ctx, pkg.ImportUser, row.Login, row.Email, row.Birthday, row.Department).Scan(&id)
+		if err != nil {
+			if utils.IsUniqueViolation(err) {
+				return nil, errs.ErrDuplicate
+			}
+			return nil, fmt.Errorf("import user %s error: %s", row.Login, err.Error())
+		}
+
+		_, err = tx.ExecContext(ctx, pkg.SetPasswordToken, id, tokenHashes[i], expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("set password token error: %s", err.Error())
+		}
+
+		ids = append(ids, id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("commit import error: %s", err.Error())
+	}
+
+	return ids, nil
+}
+
+// UsePasswordToken sets the password of the owner of an unexpired token and
+// deletes the token, so it works only once.
+func (r *ProfileRepo) UsePasswordToken(ctx context.Context, tokenHash []byte, password []byte) (bool, error) {
+	var id uint64
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin tx error: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, pkg.UsePasswordToken, tokenHash).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("use password token error: %s", err.Error())
+	}
+
+	_, err = tx.ExecContext(ctx, pkg.UpdatePassword, id, password)
+	if err != nil {
+		return false, fmt.Errorf("update password error: %s", err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("commit password error: %s", err.Error())
+	}
+
+	return true, nil
+}
+
 func (r *ProfileRepo) GetUserId(ctx context.Context, login string) (uint64, error) {
 	var userID uint64
 
@@ -127,37 +193,211 @@ func (r *ProfileRepo) GetUserId(ctx context.Context, login string) (uint64, erro
 	return userID, nil
 }
 
-func (r *ProfileRepo) GetEmployees(ctx context.Context, offset, limit uint64) ([]*models.UserItem, error) {
-	users := make([]*models.UserItem, 0)
+func (r *ProfileRepo) GetUserRole(ctx context.Context, id uint64) (string, error) {
+	var role string
 
-	rows, err := r.db.QueryContext(ctx, pkg.GetEmployees, offset, limit)
+	err := r.db.QueryRowContext(ctx, pkg.GetUserRole, id).Scan(&role)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return users, nil
+		return "", fmt.Errorf("get user role error: %s", err.Error())
+	}
+
+	return role, nil
+}
+
+func (r *ProfileRepo) UpdateRole(ctx context.Context, id uint64, role string) error {
+	res, err := r.db.ExecContext(ctx, pkg.UpdateRole, id, role)
+	if err != nil {
+		return fmt.Errorf("update role error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// SearchEmployees returns a page of employees matching filter. The cursor
+// of the last employee is returned when more employees follow.
+func (r *ProfileRepo) SearchEmployees(ctx context.Context, filter *models.EmployeeFilter) (*models.EmployeesPage, *models.EmployeeCursor, error) {
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	var sortKey string
+	switch filter.Sort {
+	case models.SortByBirthday:
+		sortKey = pkg.EmployeeSortByBirthday
+	case models.SortByUpcoming:
+		sortKey = fmt.Sprintf(pkg.EmployeeSortByUpcoming, arg(filter.LeapDayKey), arg(filter.Today))
+	default:
+		sortKey = pkg.EmployeeSortByName
+	}
+
+	conditions := []string{"TRUE"}
+	if filter.Query != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+		conditions = append(conditions, fmt.Sprintf("(login ILIKE %[1]s OR email ILIKE %[1]s)", arg(pattern)))
+	}
+
+	if filter.Month != 0 || filter.From != "" || filter.To != "" {
+		conditions = append(conditions, pkg.EmployeeBirthdayShown)
+	}
+
+	if filter.Month != 0 {
+		conditions = append(conditions, "EXTRACT(MONTH FROM birthday) = "+arg(filter.Month))
+	}
+
+	switch {
+	case filter.From != "" && filter.To != "" && filter.From > filter.To:
+		conditions = append(conditions, fmt.Sprintf("(to_char(birthday, 'MM-DD') >= %s OR to_char(birthday, 'MM-DD') <= %s)", arg(filter.From), arg(filter.To)))
+	default:
+		if filter.From != "" {
+			conditions = append(conditions, "to_char(birthday, 'MM-DD') >= "+arg(filter.From))
+		}
+		if filter.To != "" {
+			conditions = append(conditions, "to_char(birthday, 'MM-DD') <= "+arg(filter.To))
 		}
+	}
 
-		return nil, fmt.Errorf("get user query error: %s", err.Error())
+	after := "TRUE"
+	offset := filter.Offset
+	if filter.Cursor != nil {
+		after = fmt.Sprintf("(sort_key, id) > (%s, %s)", arg(filter.Cursor.Key), arg(filter.Cursor.Id))
+		offset = 0
+	}
+
+	// one more row than asked tells whether there is a next page
+	query := fmt.Sprintf(pkg.SearchEmployees, sortKey, strings.Join(conditions, " AND "), after, arg(filter.Limit+1), arg(offset))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search employees query error: %s", err.Error())
 	}
 	defer rows.Close()
 
+	page := &models.EmployeesPage{Items: make([]*models.UserItem, 0)}
+	var next *models.EmployeeCursor
+	var lastKey string
+
 	for rows.Next() {
 		user := &models.UserItem{}
+		var key string
 
-		err = rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday)
+		err = rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Visibility, &key, &page.Total)
 		if err != nil {
-			return nil, fmt.Errorf("get user rows scan error: %s", err.Error())
+			return nil, nil, fmt.Errorf("search employees rows scan error: %s", err.Error())
 		}
 
-		users = append(users, user)
+		if uint64(len(page.Items)) == filter.Limit {
+			last := page.Items[len(page.Items)-1]
+			next = &models.EmployeeCursor{Sort: filter.Sort, Key: lastKey, Id: last.Id}
+			break
+		}
+
+		page.Items = append(page.Items, user)
+		lastKey = key
 	}
 
-	return users, nil
+	return page, next, nil
+}
+
+func (r *ProfileRepo) GetEmployee(ctx context.Context, id uint64) (*models.UserItem, bool, error) {
+	user := &models.UserItem{}
+
+	err := r.db.QueryRowContext(ctx, pkg.GetEmployee, id).Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Role, &user.Visibility)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("get employee error: %s", err.Error())
+	}
+
+	return user, true, nil
+}
+
+// UpdateEmployee changes the fields of update that are not nil.
+func (r *ProfileRepo) UpdateEmployee(ctx context.Context, id uint64, update *models.ProfileRequest) error {
+	res, err := r.db.ExecContext(ctx, pkg.UpdateEmployee, id, update.Email, update.Birthday, update.Department, update.Timezone, update.Locale)
+	if err != nil {
+		return fmt.Errorf("update employee error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteEmployee removes the profile. Subscriptions and channels are removed
+// by ON DELETE CASCADE.
+func (r *ProfileRepo) DeleteEmployee(ctx context.Context, id uint64) error {
+	res, err := r.db.ExecContext(ctx, pkg.DeleteEmployee, id)
+	if err != nil {
+		return fmt.Errorf("delete employee error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
 }
 
-func (r *ProfileRepo) GetBirthdayEmployees(ctx context.Context) ([]*models.UserItem, error) {
+// GetBirthdaysBetween returns employees born between the MM-DD days from and
+// to, marking the ones subscriberId is subscribed to.
+func (r *ProfileRepo) GetBirthdaysBetween(ctx context.Context, subscriberId uint64, from, to string) ([]*models.UpcomingBirthday, error) {
+	birthdays := make([]*models.UpcomingBirthday, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetBirthdaysBetween, subscriberId, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("get birthdays query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item := &models.UpcomingBirthday{}
+
+		err = rows.Scan(&item.Id, &item.Login, &item.Email, &item.Birthday, &item.Timezone, &item.Locale, &item.Department, &item.Visibility, &item.Subscribed)
+		if err != nil {
+			return nil, fmt.Errorf("get birthdays rows scan error: %s", err.Error())
+		}
+
+		birthdays = append(birthdays, item)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get birthdays rows error: %s", err.Error())
+	}
+
+	return birthdays, nil
+}
+
+// GetBirthdayEmployees returns the employees in timezone born on one of the
+// MM-DD days.
+func (r *ProfileRepo) GetBirthdayEmployees(ctx context.Context, timezone string, days []string) ([]*models.UserItem, error) {
 	users := make([]*models.UserItem, 0)
 
-	rows, err := r.db.QueryContext(ctx, pkg.GetBirthdayEmployees)
+	rows, err := r.db.QueryContext(ctx, pkg.GetBirthdayEmployees, timezone, strings.Join(days, ","))
 	if err != nil {
 		return nil, err
 	}
@@ -165,39 +405,352 @@ func (r *ProfileRepo) GetBirthdayEmployees(ctx context.Context) ([]*models.UserI
 
 	for rows.Next() {
 		var user models.UserItem
-		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday)
+		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Visibility)
 		if err != nil {
 			return nil, err
 		}
 		users = append(users, &user)
 	}
 
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get birthday employees rows error: %s", err.Error())
+	}
+
 	return users, nil
 }
 
-func (r *ProfileRepo) GetEmployeeByBirthday(ctx context.Context, id uint64) ([]*models.UserItem, error) {
+// GetEmployeeByBirthday returns the employees to remind about id days
+// before the birthday.
+func (r *ProfileRepo) GetEmployeeByBirthday(ctx context.Context, id uint64, days int) ([]*models.UserItem, error) {
 	users := make([]*models.UserItem, 0)
 
-	rows, err := r.db.QueryContext(ctx, pkg.GetEmployeeByBirthday, id)
+	rows, err := r.db.QueryContext(ctx, pkg.GetEmployeeByBirthday, id, days)
+	if err != nil {
+		return nil, fmt.Errorf("get employee by birthday query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &models.UserItem{}
+
+		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department)
+		if err != nil {
+			return nil, fmt.Errorf("get user rows scan error: %s", err.Error())
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get employee by birthday rows error: %s", err.Error())
+	}
+
+	return users, nil
+}
+
+// GetReminderDays returns the reminder offsets in use, so the worker looks
+// only at the days someone wants to be reminded on.
+func (r *ProfileRepo) GetReminderDays(ctx context.Context) ([]int, error) {
+	days := make([]int, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetReminderDays)
+	if err != nil {
+		return nil, fmt.Errorf("get reminder days query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day int
+
+		err := rows.Scan(&day)
+		if err != nil {
+			return nil, fmt.Errorf("get reminder days scan error: %s", err.Error())
+		}
+
+		days = append(days, day)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get reminder days rows error: %s", err.Error())
+	}
+
+	return days, nil
+}
+
+func (r *ProfileRepo) GetTimezones(ctx context.Context) ([]string, error) {
+	timezones := make([]string, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetTimezones)
+	if err != nil {
+		return nil, fmt.Errorf("get timezones query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timezone string
+
+		err := rows.Scan(&timezone)
+		if err != nil {
+			return nil, fmt.Errorf("get timezones rows scan error: %s", err.Error())
+		}
+
+		timezones = append(timezones, timezone)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get timezones rows error: %s", err.Error())
+	}
+
+	return timezones, nil
+}
+
+func (r *ProfileRepo) UpdateTimezone(ctx context.Context, id uint64, timezone string) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateTimezone, id, timezone)
+	if err != nil {
+		return fmt.Errorf("update timezone error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *ProfileRepo) UpdateLocale(ctx context.Context, id uint64, locale string) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateLocale, id, locale)
+	if err != nil {
+		return fmt.Errorf("update locale error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *ProfileRepo) UpdateDeliveryMode(ctx context.Context, id uint64, mode string) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateDeliveryMode, id, mode)
+	if err != nil {
+		return fmt.Errorf("update delivery mode error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *ProfileRepo) UpdateBirthdayVisibility(ctx context.Context, id uint64, visibility string) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateBirthdayVisibility, id, visibility)
+	if err != nil {
+		return fmt.Errorf("update birthday visibility error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *ProfileRepo) UpdateAllowSubscribe(ctx context.Context, id uint64, allow bool) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateAllowSubscribe, id, allow)
+	if err != nil {
+		return fmt.Errorf("update allow subscribe error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// GetPrivacy returns the birthday visibility of id, whether id accepts
+// subscriptions and whether the profile exists.
+func (r *ProfileRepo) GetPrivacy(ctx context.Context, id uint64) (string, bool, bool, error) {
+	var visibility string
+	var allow bool
+
+	err := r.db.QueryRowContext(ctx, pkg.GetPrivacy, id).Scan(&visibility, &allow)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return users, nil
+			return "", false, false, nil
 		}
+		return "", false, false, fmt.Errorf("get privacy error: %s", err.Error())
+	}
 
-		return nil, err
+	return visibility, allow, true, nil
+}
+
+// GetDigestRecipients returns the employees of timezone who get digests in
+// one of modes.
+func (r *ProfileRepo) GetDigestRecipients(ctx context.Context, timezone string, modes []string) ([]*models.DigestRecipient, error) {
+	recipients := make([]*models.DigestRecipient, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetDigestRecipients, timezone, strings.Join(modes, ","))
+	if err != nil {
+		return nil, fmt.Errorf("get digest recipients query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		recipient := &models.DigestRecipient{}
+
+		err := rows.Scan(&recipient.Id, &recipient.Login, &recipient.Email, &recipient.Birthday, &recipient.Timezone, &recipient.Locale, &recipient.Department, &recipient.DeliveryMode)
+		if err != nil {
+			return nil, fmt.Errorf("get digest recipients scan error: %s", err.Error())
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get digest recipients rows error: %s", err.Error())
+	}
+
+	return recipients, nil
+}
+
+// GetSubscribedEmployees returns everyone id follows directly or through a
+// team.
+func (r *ProfileRepo) GetSubscribedEmployees(ctx context.Context, id uint64) ([]*models.UserItem, error) {
+	users := make([]*models.UserItem, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetSubscribedEmployees, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscribed employees query error: %s", err.Error())
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		user := &models.UserItem{}
 
-		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday)
+		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Visibility)
 		if err != nil {
-			return nil, fmt.Errorf("get user rows scan error: %s", err.Error())
+			return nil, fmt.Errorf("get subscribed employees scan error: %s", err.Error())
 		}
 
 		users = append(users, user)
 	}
 
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get subscribed employees rows error: %s", err.Error())
+	}
+
 	return users, nil
 }
+
+// StreamEmployees calls fn for every profile in id order while reading the
+// rows, so the profiles are never held in memory together.
+func (r *ProfileRepo) StreamEmployees(ctx context.Context, fn func(*models.UserItem) error) error {
+	rows, err := r.db.QueryContext(ctx, pkg.ExportEmployees)
+	if err != nil {
+		return fmt.Errorf("export employees query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &models.UserItem{}
+
+		err = rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Role)
+		if err != nil {
+			return fmt.Errorf("export employees rows scan error: %s", err.Error())
+		}
+
+		err = fn(user)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamSubscriptions calls fn for every subscription while reading the rows.
+func (r *ProfileRepo) StreamSubscriptions(ctx context.Context, fn func(*models.Subscription) error) error {
+	rows, err := r.db.QueryContext(ctx, pkg.ExportSubscriptions)
+	if err != nil {
+		return fmt.Errorf("export subscriptions query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		subscription := &models.Subscription{}
+
+		err = rows.Scan(&subscription.SubscriberId, &subscription.SubscriberLogin, &subscription.EmployeeId, &subscription.EmployeeLogin, &subscription.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("export subscriptions rows scan error: %s", err.Error())
+		}
+
+		err = fn(subscription)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *ProfileRepo) UpdateHideSubscribers(ctx context.Context, id uint64, hide bool) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateHideSubscribers, id, hide)
+	if err != nil {
+		return fmt.Errorf("update hide subscribers error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *ProfileRepo) GetHideSubscribers(ctx context.Context, id uint64) (bool, bool, error) {
+	var hide bool
+
+	err := r.db.QueryRowContext(ctx, pkg.GetHideSubscribers, id).Scan(&hide)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("get hide subscribers error: %s", err.Error())
+	}
+
+	return hide, true, nil
+}
+
+func (r *ProfileRepo) GetChannels(ctx context.Context, id uint64) ([]*models.Channel, error) {
+	channels := make([]*models.Channel, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetChannels, id)
+	if err != nil {
+		return nil, fmt.Errorf("get channels query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		channel := &models.Channel{}
+
+		err := rows.Scan(&channel.Channel, &channel.Address)
+		if err != nil {
+			return nil, fmt.Errorf("get channels rows scan error: %s", err.Error())
+		}
+
+		channels = append(channels, channel)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("get channels rows error: %s", err.Error())
+	}
+
+	return channels, nil
+}
+
+// SetChannels replaces all channels of the profile in one transaction.
+func (r *ProfileRepo) SetChannels(ctx context.Context, id uint64, channels []*models.Channel) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx error: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, pkg.DeleteChannels, id)
+	if err != nil {
+		return fmt.Errorf("delete channels error: %s", err.Error())
+	}
+
+	for _, channel := range channels {
+		_, err = tx.ExecContext(ctx, pkg.AddChannel, id, channel.Channel, channel.Address)
+		if err != nil {
+			return fmt.Errorf("add channel error: %s", err.Error())
+		}
+	}
+
+	return tx.Commit()
+}
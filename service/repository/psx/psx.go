@@ -0,0 +1,52 @@
+package psx
+
+import (
+	"database/sql"
+	"fmt"
+	_ "github.com/jackc/pgx/stdlib"
+	"github.com/sirupsen/logrus"
+	"time"
+	"vk-rest/configs"
+	utils "vk-rest/pkg"
+)
+
+// GetPsxDb opens the Postgres pool shared by all repositories of a process
+// and waits until the database answers.
+func GetPsxDb(config *configs.DbPsxConfig, log *logrus.Logger) (*sql.DB, error) {
+	dsn := fmt.Sprintf("user=%s dbname=%s password= %s host=%s port=%d sslmode=%s",
+		config.User, config.Dbname, config.Password, config.Host, config.Port, config.Sslmode)
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		log.Errorf("sql open error: %s", err.Error())
+		return nil, fmt.Errorf("get psx db err: %s", err.Error())
+	}
+
+	err = pingDb(db, 3, log)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	log.Info("Successfully connected to database")
+
+	return db, nil
+}
+
+func pingDb(db *sql.DB, timer uint32, log *logrus.Logger) error {
+	var err error
+	var retries int
+
+	for retries < utils.MaxRetries {
+		err = db.Ping()
+		if err == nil {
+			return nil
+		}
+
+		retries++
+		log.Errorf("sql ping error: %s", err.Error())
+		time.Sleep(time.Duration(timer) * time.Second)
+	}
+
+	return fmt.Errorf("sql max pinging error: %s", err.Error())
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS subscriber;
+DROP TABLE IF EXISTS profile;
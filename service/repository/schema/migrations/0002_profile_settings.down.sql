@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS profile_channel;
+DROP TABLE IF EXISTS feed_token;
+DROP TABLE IF EXISTS password_token;
+
+ALTER TABLE subscriber
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS reminders;
+
+ALTER TABLE profile
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS allow_subscribe,
+    DROP COLUMN IF EXISTS birthday_visibility,
+    DROP COLUMN IF EXISTS delivery_mode,
+    DROP COLUMN IF EXISTS hide_subscribers,
+    DROP COLUMN IF EXISTS role,
+    DROP COLUMN IF EXISTS department,
+    DROP COLUMN IF EXISTS locale,
+    DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,42 @@
+ALTER TABLE profile
+    ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC',
+    ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'ru',
+    ADD COLUMN IF NOT EXISTS department TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'employee' CHECK (role IN ('employee', 'hr', 'admin')),
+    ADD COLUMN IF NOT EXISTS hide_subscribers BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS delivery_mode TEXT NOT NULL DEFAULT 'immediate' CHECK (delivery_mode IN ('immediate', 'daily', 'weekly')),
+    ADD COLUMN IF NOT EXISTS birthday_visibility TEXT NOT NULL DEFAULT 'full' CHECK (birthday_visibility IN ('full', 'no_year', 'hidden')),
+    ADD COLUMN IF NOT EXISTS allow_subscribe BOOLEAN NOT NULL DEFAULT true,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE subscriber
+    ADD COLUMN IF NOT EXISTS reminders INT[] NOT NULL DEFAULT '{0}',
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE TABLE IF NOT EXISTS password_token(
+    profile_id INT NOT NULL PRIMARY KEY REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    token_hash bytea NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS feed_token(
+    profile_id INT NOT NULL PRIMARY KEY REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    token_hash bytea NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS profile_channel(
+    profile_id INT NOT NULL REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    channel TEXT NOT NULL,
+    address TEXT NOT NULL DEFAULT '',
+
+    PRIMARY KEY(profile_id, channel)
+);
+
+UPDATE profile SET role = 'admin' WHERE login = 'admin' AND role = 'employee';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS outbox_attempt;
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS template;
+DROP TABLE IF EXISTS card;
@@ -0,0 +1,70 @@
+CREATE TABLE IF NOT EXISTS card(
+    id SERIAL NOT NULL PRIMARY KEY,
+    content_type TEXT NOT NULL,
+    data BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS template(
+    id SERIAL NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL,
+    locale TEXT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    card_id INT REFERENCES card(id)
+    ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE(name, locale)
+);
+
+CREATE TABLE IF NOT EXISTS outbox(
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    recipient_id INT NOT NULL REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    birthday_id INT NOT NULL REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    year INT NOT NULL,
+    kind TEXT NOT NULL,
+    channel TEXT NOT NULL DEFAULT 'email',
+    address TEXT NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL DEFAULT '',
+    body_html TEXT NOT NULL DEFAULT '',
+    card_id INT REFERENCES card(id)
+    ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ,
+
+    UNIQUE(recipient_id, birthday_id, year, kind, channel)
+);
+
+CREATE TABLE IF NOT EXISTS outbox_attempt(
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    outbox_id BIGINT NOT NULL REFERENCES outbox(id)
+    ON DELETE CASCADE,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    success BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(next_attempt_at) WHERE status = 'pending';
+
+INSERT INTO template(name, locale, subject, body) VALUES
+    ('greeting', 'ru', '{{if .Belated}}Запоздалое поздравление{{else}}Поздравление{{end}}', '{{if .Belated}}С опозданием поздравляем{{else}}Поздравляем{{end}} Вас с днём рождения!'),
+    ('greeting', 'en', 'Happy {{if .Belated}}belated {{end}}birthday', 'Happy {{if .Belated}}belated {{end}}birthday, {{.Name}}!'),
+    ('notify', 'ru', '{{if .Belated}}Прошедший день рождения{{else}}Поздравление{{end}}', '{{if .Belated}}{{.Date}} был день рождения у {{.Name}}, ещё не поздно поздравить!{{else}}Сегодня день рождения у {{.Name}}, не забудьте поздравить!{{end}}'),
+    ('notify', 'en', '{{if .Belated}}Belated birthday{{else}}Birthday{{end}}', '{{if .Belated}}{{.Date}} was {{.Name}}''s birthday, it is not too late to congratulate!{{else}}Today is {{.Name}}''s birthday, do not forget to congratulate!{{end}}'),
+    ('reminder', 'ru', 'Скоро день рождения', 'Через {{.DaysUntil}} дн. день рождения у {{.Name}}, самое время подготовить подарок!'),
+    ('reminder', 'en', 'Upcoming birthday', '{{.Name}}''s birthday is in {{.DaysUntil}} days, time to prepare a gift!'),
+    ('digest', 'ru', 'Дни рождения коллег', 'Дни рождения коллег, на которых Вы подписаны:{{range .Birthdays}}
+{{.Date}} — {{.Name}}{{end}}'),
+    ('digest', 'en', 'Birthdays of your colleagues', 'Birthdays of the colleagues you follow:{{range .Birthdays}}
+{{.Date}}: {{.Name}}{{end}}')
+    ON CONFLICT (name, locale) DO NOTHING;
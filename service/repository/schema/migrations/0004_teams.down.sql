@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS team_subscriber;
+DROP TABLE IF EXISTS team_member;
+DROP TABLE IF EXISTS team;
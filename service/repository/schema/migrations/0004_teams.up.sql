@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS team(
+    id SERIAL NOT NULL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS team_member(
+    team_id INT NOT NULL REFERENCES team(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    profile_id INT NOT NULL REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY(team_id, profile_id)
+);
+
+CREATE TABLE IF NOT EXISTS team_subscriber(
+    team_id INT NOT NULL REFERENCES team(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    profile_id INT NOT NULL REFERENCES profile(id)
+    ON DELETE CASCADE
+    ON UPDATE CASCADE,
+    reminders INT[] NOT NULL DEFAULT '{0}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY(team_id, profile_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_member_profile ON team_member(profile_id);
+CREATE INDEX IF NOT EXISTS idx_team_subscriber_profile ON team_subscriber(profile_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS worker_run;
+DROP TABLE IF EXISTS worker_state;
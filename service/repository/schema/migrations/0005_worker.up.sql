@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS worker_state(
+    id BOOLEAN NOT NULL PRIMARY KEY DEFAULT true CHECK (id),
+    schedule TEXT NOT NULL DEFAULT '0 */15 * * * *',
+    paused BOOLEAN NOT NULL DEFAULT false,
+    fence BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS worker_run(
+    timezone TEXT NOT NULL PRIMARY KEY,
+    last_run_date DATE NOT NULL
+);
+
+INSERT INTO worker_state DEFAULT VALUES ON CONFLICT (id) DO NOTHING;
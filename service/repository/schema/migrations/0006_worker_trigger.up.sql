@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS worker_trigger(
+    id BIGSERIAL NOT NULL PRIMARY KEY,
+    date DATE NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT false,
+    status TEXT NOT NULL DEFAULT 'pending',
+    messages JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS worker_trigger_open ON worker_trigger(id) WHERE status IN ('pending', 'running');
@@ -0,0 +1,262 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+)
+
+// Migrations are named NNNN_name.up.sql and NNNN_name.down.sql. Every step
+// must be safe to apply to a database created before the migrations existed.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type ISchemaRepo interface {
+	Status(ctx context.Context) ([]*models.Migration, error)
+	Up(ctx context.Context) ([]*models.Migration, error)
+	Down(ctx context.Context, steps int) ([]*models.Migration, error)
+}
+
+type SchemaRepo struct {
+	db         *sql.DB
+	log        *logrus.Logger
+	migrations []*models.Migration
+}
+
+func GetSchemaRepo(db *sql.DB, log *logrus.Logger) (*SchemaRepo, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		log.Errorf("load migrations error: %s", err.Error())
+		return nil, fmt.Errorf("get schema repo err: %s", err.Error())
+	}
+
+	return &SchemaRepo{db: db, log: log, migrations: migrations}, nil
+}
+
+// loadMigrations reads the embedded migrations sorted by version. Every
+// version must have both an up and a down step.
+func loadMigrations() ([]*models.Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations error: %s", err.Error())
+	}
+
+	byVersion := make(map[int]*models.Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("bad migration file name: %s", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("bad migration version: %s", entry.Name())
+		}
+
+		data, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s error: %s", entry.Name(), err.Error())
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &models.Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]*models.Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d must have up and down steps", migration.Version)
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Status returns the known migrations with the time they were applied.
+// Versions applied by a newer build are listed without steps.
+func (r *SchemaRepo) Status(ctx context.Context) ([]*models.Migration, error) {
+	_, err := r.db.ExecContext(ctx, pkg.CreateSchemaVersion)
+	if err != nil {
+		return nil, fmt.Errorf("create schema version error: %s", err.Error())
+	}
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetSchemaVersions)
+	if err != nil {
+		return nil, fmt.Errorf("get schema versions error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	applied := make(map[int]*models.Migration)
+	for rows.Next() {
+		migration := &models.Migration{}
+		var appliedAt time.Time
+
+		err := rows.Scan(&migration.Version, &migration.Name, &appliedAt)
+		if err != nil {
+			return nil, fmt.Errorf("get schema versions scan error: %s", err.Error())
+		}
+
+		migration.AppliedAt = &appliedAt
+		applied[migration.Version] = migration
+	}
+
+	status := make([]*models.Migration, 0, len(r.migrations))
+	for _, known := range r.migrations {
+		migration := *known
+		if stored, ok := applied[known.Version]; ok {
+			migration.AppliedAt = stored.AppliedAt
+			delete(applied, known.Version)
+		}
+
+		status = append(status, &migration)
+	}
+
+	for _, unknown := range applied {
+		status = append(status, unknown)
+	}
+
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Version < status[j].Version
+	})
+
+	return status, nil
+}
+
+// Up applies the pending migrations in order and returns them.
+func (r *SchemaRepo) Up(ctx context.Context) ([]*models.Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]*models.Migration, 0)
+	for _, migration := range status {
+		if migration.AppliedAt != nil {
+			continue
+		}
+
+		applied, err := r.step(ctx, migration, true)
+		if err != nil {
+			return done, err
+		}
+
+		if applied {
+			r.log.Infof("Applied migration %04d_%s", migration.Version, migration.Name)
+			done = append(done, migration)
+		}
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// them.
+func (r *SchemaRepo) Down(ctx context.Context, steps int) ([]*models.Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]*models.Migration, 0)
+	for i := len(status) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := status[i]
+		if migration.AppliedAt == nil {
+			continue
+		}
+
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %d is unknown to this build", migration.Version)
+		}
+
+		reverted, err := r.step(ctx, migration, false)
+		if err != nil {
+			return done, err
+		}
+
+		if reverted {
+			r.log.Infof("Reverted migration %04d_%s", migration.Version, migration.Name)
+			done = append(done, migration)
+		}
+	}
+
+	return done, nil
+}
+
+// step applies or reverts migration in a transaction that holds the schema
+// lock. It reports false when another migrator has already done the step.
+func (r *SchemaRepo) step(ctx context.Context, migration *models.Migration, up bool) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin migration %d error: %s", migration.Version, err.Error())
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, pkg.LockSchema)
+	if err != nil {
+		return false, fmt.Errorf("lock schema error: %s", err.Error())
+	}
+
+	var applied bool
+	err = tx.QueryRowContext(ctx, pkg.SchemaVersionApplied, migration.Version).Scan(&applied)
+	if err != nil {
+		return false, fmt.Errorf("schema version applied error: %s", err.Error())
+	}
+
+	if applied == up {
+		return false, nil
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, migration.Up)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, pkg.AddSchemaVersion, migration.Version, migration.Name)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, pkg.DeleteSchemaVersion, migration.Version)
+		}
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("migration %04d_%s error: %s", migration.Version, migration.Name, err.Error())
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, fmt.Errorf("commit migration %d error: %s", migration.Version, err.Error())
+	}
+
+	return true, nil
+}
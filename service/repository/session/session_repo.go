@@ -5,15 +5,24 @@ import (
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/sirupsen/logrus"
+	"strconv"
 	"time"
 	"vk-rest/configs"
 	"vk-rest/pkg/models"
 )
 
+const (
+	sessionPrefix     = "session:"
+	userSessionPrefix = "user_sessions:"
+)
+
 type ISessionRepo interface {
-	AddSession(ctx context.Context, active models.Session) (bool, error)
+	AddSession(ctx context.Context, active models.Session, ttl time.Duration) (bool, error)
 	CheckActiveSession(ctx context.Context, sid string) (bool, error)
 	GetUserLogin(ctx context.Context, sid string) (string, error)
+	GetSession(ctx context.Context, sid string) (*models.Session, bool, error)
+	TouchSession(ctx context.Context, sid string, lastSeen time.Time, ttl time.Duration) error
+	GetUserSessions(ctx context.Context, login string) ([]*models.Session, error)
 	DeleteSession(ctx context.Context, sid string) (bool, error)
 }
 
@@ -38,9 +47,26 @@ func GetAuthRepo(cfg *configs.DbRedisCfg, log *logrus.Logger) (ISessionRepo, err
 	return &SessionRepo{DB: redisClient}, nil
 }
 
-func (repo *SessionRepo) AddSession(ctx context.Context, active models.Session) (bool, error) {
-
-	repo.DB.Set(ctx, active.SID, active.Login, 24*time.Hour)
+// AddSession stores the session as a hash that expires after ttl and adds
+// it to the set of sessions of its user.
+func (repo *SessionRepo) AddSession(ctx context.Context, active models.Session, ttl time.Duration) (bool, error) {
+	key := sessionPrefix + active.SID
+
+	_, err := repo.DB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"login", active.Login,
+			"created_at", active.CreatedAt.Unix(),
+			"last_seen", active.LastSeen.Unix(),
+			"ip", active.IP,
+			"user_agent", active.UserAgent,
+		)
+		pipe.Expire(ctx, key, ttl)
+		pipe.SAdd(ctx, userSessionPrefix+active.Login, active.SID)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("add session to redis failed: %s", err.Error())
+	}
 
 	added, err := repo.CheckActiveSession(ctx, active.SID)
 	if err != nil {
@@ -51,20 +77,20 @@ func (repo *SessionRepo) AddSession(ctx context.Context, active models.Session)
 }
 
 func (repo *SessionRepo) CheckActiveSession(ctx context.Context, sid string) (bool, error) {
-	_, err := repo.DB.Get(ctx, sid).Result()
-	if err == redis.Nil {
-		return false, fmt.Errorf("key %s not found", sid)
-	}
-
+	exists, err := repo.DB.Exists(ctx, sessionPrefix+sid).Result()
 	if err != nil {
 		return false, fmt.Errorf("get session id %s from redis failed", sid)
 	}
 
-	return true, err
+	if exists == 0 {
+		return false, fmt.Errorf("key %s not found", sid)
+	}
+
+	return true, nil
 }
 
 func (repo *SessionRepo) GetUserLogin(ctx context.Context, sid string) (string, error) {
-	value, err := repo.DB.Get(ctx, sid).Result()
+	value, err := repo.DB.HGet(ctx, sessionPrefix+sid, "login").Result()
 	if err != nil {
 		return "", fmt.Errorf("cannot find session %s", sid)
 	}
@@ -72,11 +98,91 @@ func (repo *SessionRepo) GetUserLogin(ctx context.Context, sid string) (string,
 	return value, nil
 }
 
+func (repo *SessionRepo) GetSession(ctx context.Context, sid string) (*models.Session, bool, error) {
+	values, err := repo.DB.HGetAll(ctx, sessionPrefix+sid).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("get session %s from redis failed", sid)
+	}
+
+	if len(values) == 0 {
+		return nil, false, nil
+	}
+
+	return parseSession(sid, values), true, nil
+}
+
+func (repo *SessionRepo) TouchSession(ctx context.Context, sid string, lastSeen time.Time, ttl time.Duration) error {
+	key := sessionPrefix + sid
+
+	_, err := repo.DB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "last_seen", lastSeen.Unix())
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("touch session %s failed: %s", sid, err.Error())
+	}
+
+	return nil
+}
+
+// GetUserSessions returns the live sessions of login and forgets the ones
+// that have already expired.
+func (repo *SessionRepo) GetUserSessions(ctx context.Context, login string) ([]*models.Session, error) {
+	sessions := make([]*models.Session, 0)
+
+	sids, err := repo.DB.SMembers(ctx, userSessionPrefix+login).Result()
+	if err != nil {
+		return nil, fmt.Errorf("get sessions of %s failed: %s", login, err.Error())
+	}
+
+	for _, sid := range sids {
+		active, found, err := repo.GetSession(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !found {
+			repo.DB.SRem(ctx, userSessionPrefix+login, sid)
+			continue
+		}
+
+		sessions = append(sessions, active)
+	}
+
+	return sessions, nil
+}
+
 func (repo *SessionRepo) DeleteSession(ctx context.Context, sid string) (bool, error) {
-	_, err := repo.DB.Del(ctx, sid).Result()
+	login, err := repo.DB.HGet(ctx, sessionPrefix+sid, "login").Result()
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("cannot delete session %s", sid)
+	}
+
+	_, err = repo.DB.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionPrefix+sid)
+		if login != "" {
+			pipe.SRem(ctx, userSessionPrefix+login, sid)
+		}
+		return nil
+	})
 	if err != nil {
 		return false, fmt.Errorf("cannot delete session %s", sid)
 	}
 
 	return true, nil
 }
+
+func parseSession(sid string, values map[string]string) *models.Session {
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(values["last_seen"], 10, 64)
+
+	return &models.Session{
+		Login:     values["login"],
+		SID:       sid,
+		CreatedAt: time.Unix(createdAt, 0),
+		LastSeen:  time.Unix(lastSeen, 0),
+		IP:        values["ip"],
+		UserAgent: values["user_agent"],
+	}
+}
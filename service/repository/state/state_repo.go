@@ -0,0 +1,194 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	utils "vk-rest/pkg"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+)
+
+type IStateRepo interface {
+	GetWorkerState(ctx context.Context) (*models.WorkerState, error)
+	UpdateSchedule(ctx context.Context, schedule string) error
+	UpdatePaused(ctx context.Context, paused bool) error
+	GetLastRuns(ctx context.Context) (map[string]time.Time, error)
+	SetLastRun(ctx context.Context, timezone string, day time.Time) error
+	NextFence(ctx context.Context) (int64, error)
+	AddTrigger(ctx context.Context, date time.Time, dryRun bool) (uint64, error)
+	GetTrigger(ctx context.Context, id uint64) (*models.WorkerTrigger, bool, error)
+	HasOpenTriggers(ctx context.Context) (bool, error)
+	ClaimTrigger(ctx context.Context, staleBefore time.Time) (*models.WorkerTrigger, bool, error)
+	FinishTrigger(ctx context.Context, trigger *models.WorkerTrigger) error
+}
+
+type StateRepo struct {
+	db *sql.DB
+}
+
+func GetStateRepo(db *sql.DB) *StateRepo {
+	return &StateRepo{db: db}
+}
+
+// GetWorkerState returns the stored state of the birthday job, or the default
+// schedule when nothing is stored.
+func (r *StateRepo) GetWorkerState(ctx context.Context) (*models.WorkerState, error) {
+	state := &models.WorkerState{}
+
+	err := r.db.QueryRowContext(ctx, pkg.GetWorkerState).Scan(&state.Schedule, &state.Paused, &state.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &models.WorkerState{Schedule: utils.DefaultSchedule}, nil
+		}
+		return nil, fmt.Errorf("get worker state error: %s", err.Error())
+	}
+
+	return state, nil
+}
+
+func (r *StateRepo) UpdateSchedule(ctx context.Context, schedule string) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateWorkerSchedule, schedule)
+	if err != nil {
+		return fmt.Errorf("update worker schedule error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *StateRepo) UpdatePaused(ctx context.Context, paused bool) error {
+	_, err := r.db.ExecContext(ctx, pkg.UpdateWorkerPaused, utils.DefaultSchedule, paused)
+	if err != nil {
+		return fmt.Errorf("update worker paused error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// GetLastRuns returns the last date the birthday job was done for by time
+// zone.
+func (r *StateRepo) GetLastRuns(ctx context.Context) (map[string]time.Time, error) {
+	runs := make(map[string]time.Time)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetLastRuns)
+	if err != nil {
+		return nil, fmt.Errorf("get last runs error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timezone string
+		var day time.Time
+
+		err := rows.Scan(&timezone, &day)
+		if err != nil {
+			return nil, fmt.Errorf("get last runs scan error: %s", err.Error())
+		}
+
+		runs[timezone] = day
+	}
+
+	return runs, nil
+}
+
+func (r *StateRepo) SetLastRun(ctx context.Context, timezone string, day time.Time) error {
+	_, err := r.db.ExecContext(ctx, pkg.SetLastRun, timezone, day.Format(time.DateOnly))
+	if err != nil {
+		return fmt.Errorf("set last run error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// NextFence returns a fencing token greater than every token issued before.
+func (r *StateRepo) NextFence(ctx context.Context) (int64, error) {
+	var fence int64
+
+	err := r.db.QueryRowContext(ctx, pkg.NextFence, utils.DefaultSchedule).Scan(&fence)
+	if err != nil {
+		return 0, fmt.Errorf("next fence error: %s", err.Error())
+	}
+
+	return fence, nil
+}
+
+func (r *StateRepo) AddTrigger(ctx context.Context, date time.Time, dryRun bool) (uint64, error) {
+	var id uint64
+
+	err := r.db.QueryRowContext(ctx, pkg.AddTrigger, date.Format(time.DateOnly), dryRun).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("add trigger error: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (r *StateRepo) GetTrigger(ctx context.Context, id uint64) (*models.WorkerTrigger, bool, error) {
+	trigger := &models.WorkerTrigger{}
+	var date time.Time
+	var messages []byte
+
+	err := r.db.QueryRowContext(ctx, pkg.GetTrigger, id).Scan(&trigger.Id, &date, &trigger.DryRun, &trigger.Status, &messages, &trigger.Error, &trigger.CreatedAt, &trigger.FinishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("get trigger error: %s", err.Error())
+	}
+
+	err = json.Unmarshal(messages, &trigger.Messages)
+	if err != nil {
+		return nil, false, fmt.Errorf("get trigger messages error: %s", err.Error())
+	}
+
+	trigger.Date = date.Format(time.DateOnly)
+	return trigger, true, nil
+}
+
+// HasOpenTriggers reports whether some trigger is not done yet.
+func (r *StateRepo) HasOpenTriggers(ctx context.Context) (bool, error) {
+	var open bool
+
+	err := r.db.QueryRowContext(ctx, pkg.HasOpenTriggers).Scan(&open)
+	if err != nil {
+		return false, fmt.Errorf("has open triggers error: %s", err.Error())
+	}
+
+	return open, nil
+}
+
+// ClaimTrigger marks the oldest pending trigger as running and returns it.
+// A trigger left running since before staleBefore is claimed again.
+func (r *StateRepo) ClaimTrigger(ctx context.Context, staleBefore time.Time) (*models.WorkerTrigger, bool, error) {
+	trigger := &models.WorkerTrigger{Status: models.TriggerRunning}
+	var date time.Time
+
+	err := r.db.QueryRowContext(ctx, pkg.ClaimTrigger, staleBefore).Scan(&trigger.Id, &date, &trigger.DryRun, &trigger.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("claim trigger error: %s", err.Error())
+	}
+
+	trigger.Date = date.Format(time.DateOnly)
+	return trigger, true, nil
+}
+
+// FinishTrigger stores the status, messages and error of a trigger.
+func (r *StateRepo) FinishTrigger(ctx context.Context, trigger *models.WorkerTrigger) error {
+	messages, err := json.Marshal(trigger.Messages)
+	if err != nil {
+		return fmt.Errorf("finish trigger messages error: %s", err.Error())
+	}
+
+	_, err = r.db.ExecContext(ctx, pkg.FinishTrigger, trigger.Id, trigger.Status, string(messages), trigger.Error)
+	if err != nil {
+		return fmt.Errorf("finish trigger error: %s", err.Error())
+	}
+
+	return nil
+}
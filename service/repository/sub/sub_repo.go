@@ -4,9 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"github.com/sirupsen/logrus"
-	"time"
-	"vk-rest/configs"
 	utils "vk-rest/pkg"
 	errs "vk-rest/pkg/errors"
 	"vk-rest/pkg/models"
@@ -14,64 +11,23 @@ import (
 )
 
 type ISubRepo interface {
-	BirthdaySub(ctx context.Context, userId, subscriberId uint64) (bool, error)
+	BirthdaySub(ctx context.Context, userId, subscriberId uint64, reminders []int) (bool, error)
+	UpdateReminders(ctx context.Context, userId, subscriberId uint64, reminders []int) error
 	BirthdayUnSub(ctx context.Context, userId, subscriberId uint64) (bool, error)
-	GetEmployeesBySubId(ctx context.Context, subId uint64) ([]*models.UserItem, error)
+	GetEmployeesBySubId(ctx context.Context, subId, offset, limit uint64) (*models.EmployeesPage, error)
+	GetSubscribers(ctx context.Context, id, offset, limit uint64) (*models.EmployeesPage, error)
 }
 
 type SubRepo struct {
 	db *sql.DB
 }
 
-func GetSubRepo(config *configs.DbPsxConfig, log *logrus.Logger) (*SubRepo, error) {
-	dsn := fmt.Sprintf("user=%s dbname=%s password= %s host=%s port=%d sslmode=%s",
-		config.User, config.Dbname, config.Password, config.Host, config.Port, config.Sslmode)
-	db, err := sql.Open("pgx", dsn)
-	if err != nil {
-		log.Errorf("sql open error: %s", err.Error())
-		return nil, fmt.Errorf("get user repo err: %s", err.Error())
-	}
-
-	repo := &SubRepo{db: db}
-
-	errs := make(chan error)
-	go func() {
-		errs <- repo.pingDb(3, log)
-	}()
-
-	if err := <-errs; err != nil {
-		log.Error(err.Error())
-		return nil, err
-	}
-
-	db.SetMaxOpenConns(config.MaxOpenConns)
-	log.Info("Successfully connected to database")
-
-	return repo, nil
+func GetSubRepo(db *sql.DB) *SubRepo {
+	return &SubRepo{db: db}
 }
 
-func (r *SubRepo) pingDb(timer uint32, log *logrus.Logger) error {
-	var err error
-	var retries int
-
-	for retries < utils.MaxRetries {
-		err = r.db.Ping()
-		if err == nil {
-			return nil
-		}
-
-		retries++
-		log.Errorf("sql ping error: %s", err.Error())
-		time.Sleep(time.Duration(timer) * time.Second)
-	}
-
-	return fmt.Errorf("sql max pinging error: %s", err.Error())
-}
-
-func (r *SubRepo) BirthdaySub(ctx context.Context, userId, subscriberId uint64) (bool, error) {
-	fmt.Printf("%d %d", userId, subscriberId)
-
-	res, err := r.db.ExecContext(ctx, pkg.BirthdaySub, userId, subscriberId)
+func (r *SubRepo) BirthdaySub(ctx context.Context, userId, subscriberId uint64, reminders []int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, pkg.BirthdaySub, userId, subscriberId, utils.JoinInts(reminders))
 	if err != nil {
 		if err.Error() == errs.ErrDuplicateSub.Error() {
 			return false, err
@@ -110,10 +66,40 @@ func (r *SubRepo) BirthdayUnSub(ctx context.Context, userId, subscriberId uint64
 	return true, nil
 }
 
-func (r *SubRepo) GetEmployeesBySubId(ctx context.Context, subId uint64) ([]*models.UserItem, error) {
-	users := make([]*models.UserItem, 0)
+func (r *SubRepo) UpdateReminders(ctx context.Context, userId, subscriberId uint64, reminders []int) error {
+	res, err := r.db.ExecContext(ctx, pkg.UpdateReminders, userId, subscriberId, utils.JoinInts(reminders))
+	if err != nil {
+		return fmt.Errorf("update reminders err: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
 
-	rows, err := r.db.QueryContext(ctx, pkg.GetEmployeesBySubId, subId)
+	return nil
+}
+
+// GetEmployeesBySubId returns a page of the employees subId is subscribed to,
+// directly or through a team.
+func (r *SubRepo) GetEmployeesBySubId(ctx context.Context, subId, offset, limit uint64) (*models.EmployeesPage, error) {
+	return r.getPage(ctx, pkg.GetEmployeesBySubId, subId, offset, limit)
+}
+
+// GetSubscribers returns a page of the employees subscribed to id, directly
+// or through a team.
+func (r *SubRepo) GetSubscribers(ctx context.Context, id, offset, limit uint64) (*models.EmployeesPage, error) {
+	return r.getPage(ctx, pkg.GetSubscribers, id, offset, limit)
+}
+
+func (r *SubRepo) getPage(ctx context.Context, query string, id, offset, limit uint64) (*models.EmployeesPage, error) {
+	page := &models.EmployeesPage{Items: make([]*models.UserItem, 0)}
+
+	rows, err := r.db.QueryContext(ctx, query, id, offset, limit)
 	if err != nil {
 		return nil, fmt.Errorf("get users err: %s", err.Error())
 	}
@@ -122,14 +108,13 @@ func (r *SubRepo) GetEmployeesBySubId(ctx context.Context, subId uint64) ([]*mod
 	for rows.Next() {
 		user := &models.UserItem{}
 
-		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday)
+		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Visibility, &page.Total)
 		if err != nil {
 			return nil, fmt.Errorf("get users err: %s", err.Error())
 		}
 
-		users = append(users, user)
-
+		page.Items = append(page.Items, user)
 	}
 
-	return users, nil
+	return page, nil
 }
@@ -0,0 +1,196 @@
+package team
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	utils "vk-rest/pkg"
+	errs "vk-rest/pkg/errors"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+)
+
+type ITeamRepo interface {
+	GetTeams(ctx context.Context, userId uint64) ([]*models.Team, error)
+	CreateTeam(ctx context.Context, name string) (uint64, error)
+	UpdateTeam(ctx context.Context, team *models.TeamRequest) error
+	DeleteTeam(ctx context.Context, id uint64) error
+	GetMembers(ctx context.Context, teamId, offset, limit uint64) (*models.EmployeesPage, bool, error)
+	AddMember(ctx context.Context, teamId, userId uint64) error
+	RemoveMember(ctx context.Context, teamId, userId uint64) error
+	Subscribe(ctx context.Context, teamId, userId uint64, reminders []int) error
+	UpdateReminders(ctx context.Context, teamId, userId uint64, reminders []int) error
+	Unsubscribe(ctx context.Context, teamId, userId uint64) error
+}
+
+type TeamRepo struct {
+	db *sql.DB
+}
+
+func GetTeamRepo(db *sql.DB) *TeamRepo {
+	return &TeamRepo{db: db}
+}
+
+// GetTeams returns all teams with the number of members and whether userId
+// is subscribed to them.
+func (r *TeamRepo) GetTeams(ctx context.Context, userId uint64) ([]*models.Team, error) {
+	teams := make([]*models.Team, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetTeams, userId)
+	if err != nil {
+		return nil, fmt.Errorf("get teams query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		team := &models.Team{}
+
+		err := rows.Scan(&team.Id, &team.Name, &team.Members, &team.Subscribed)
+		if err != nil {
+			return nil, fmt.Errorf("get teams scan error: %s", err.Error())
+		}
+
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+func (r *TeamRepo) CreateTeam(ctx context.Context, name string) (uint64, error) {
+	var id uint64
+
+	err := r.db.QueryRowContext(ctx, pkg.CreateTeam, name).Scan(&id)
+	if err != nil {
+		if utils.IsUniqueViolation(err) {
+			return 0, errs.ErrDuplicate
+		}
+		return 0, fmt.Errorf("create team error: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (r *TeamRepo) UpdateTeam(ctx context.Context, team *models.TeamRequest) error {
+	res, err := r.db.ExecContext(ctx, pkg.UpdateTeam, team.Id, team.Name)
+	if err != nil {
+		if utils.IsUniqueViolation(err) {
+			return errs.ErrDuplicate
+		}
+		return fmt.Errorf("update team error: %s", err.Error())
+	}
+
+	return checkAffected(res)
+}
+
+func (r *TeamRepo) DeleteTeam(ctx context.Context, id uint64) error {
+	res, err := r.db.ExecContext(ctx, pkg.DeleteTeam, id)
+	if err != nil {
+		return fmt.Errorf("delete team error: %s", err.Error())
+	}
+
+	return checkAffected(res)
+}
+
+// GetMembers returns a page of the members of teamId and false if there is
+// no such team.
+func (r *TeamRepo) GetMembers(ctx context.Context, teamId, offset, limit uint64) (*models.EmployeesPage, bool, error) {
+	var exists bool
+
+	err := r.db.QueryRowContext(ctx, pkg.TeamExists, teamId).Scan(&exists)
+	if err != nil {
+		return nil, false, fmt.Errorf("team exists query error: %s", err.Error())
+	}
+
+	if !exists {
+		return nil, false, nil
+	}
+
+	page := &models.EmployeesPage{Items: make([]*models.UserItem, 0)}
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetTeamMembers, teamId, offset, limit)
+	if err != nil {
+		return nil, false, fmt.Errorf("get team members error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := &models.UserItem{}
+
+		err := rows.Scan(&user.Id, &user.Login, &user.Email, &user.Birthday, &user.Timezone, &user.Locale, &user.Department, &user.Visibility, &page.Total)
+		if err != nil {
+			return nil, false, fmt.Errorf("get team members error: %s", err.Error())
+		}
+
+		page.Items = append(page.Items, user)
+	}
+
+	return page, true, nil
+}
+
+// AddMember adds userId to teamId. Adding a member twice is not an error.
+func (r *TeamRepo) AddMember(ctx context.Context, teamId, userId uint64) error {
+	_, err := r.db.ExecContext(ctx, pkg.AddTeamMember, teamId, userId)
+	if err != nil {
+		if utils.IsForeignKeyViolation(err) {
+			return errs.ErrNotFound
+		}
+		return fmt.Errorf("add team member error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *TeamRepo) RemoveMember(ctx context.Context, teamId, userId uint64) error {
+	res, err := r.db.ExecContext(ctx, pkg.RemoveTeamMember, teamId, userId)
+	if err != nil {
+		return fmt.Errorf("remove team member error: %s", err.Error())
+	}
+
+	return checkAffected(res)
+}
+
+func (r *TeamRepo) Subscribe(ctx context.Context, teamId, userId uint64, reminders []int) error {
+	_, err := r.db.ExecContext(ctx, pkg.TeamSub, teamId, userId, utils.JoinInts(reminders))
+	if err != nil {
+		if utils.IsUniqueViolation(err) {
+			return errs.ErrDuplicate
+		}
+		if utils.IsForeignKeyViolation(err) {
+			return errs.ErrNotFound
+		}
+		return fmt.Errorf("team subscribe error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (r *TeamRepo) UpdateReminders(ctx context.Context, teamId, userId uint64, reminders []int) error {
+	res, err := r.db.ExecContext(ctx, pkg.UpdateTeamReminders, teamId, userId, utils.JoinInts(reminders))
+	if err != nil {
+		return fmt.Errorf("update team reminders error: %s", err.Error())
+	}
+
+	return checkAffected(res)
+}
+
+func (r *TeamRepo) Unsubscribe(ctx context.Context, teamId, userId uint64) error {
+	res, err := r.db.ExecContext(ctx, pkg.TeamUnSub, teamId, userId)
+	if err != nil {
+		return fmt.Errorf("team unsubscribe error: %s", err.Error())
+	}
+
+	return checkAffected(res)
+}
+
+func checkAffected(res sql.Result) error {
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
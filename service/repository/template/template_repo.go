@@ -0,0 +1,183 @@
+package template
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	utils "vk-rest/pkg"
+	errs "vk-rest/pkg/errors"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+)
+
+type ITemplateRepo interface {
+	GetTemplates(ctx context.Context) ([]*models.Template, error)
+	GetTemplate(ctx context.Context, id uint64) (*models.Template, bool, error)
+	FindTemplate(ctx context.Context, name, locale string) (*models.Template, bool, error)
+	CreateTemplate(ctx context.Context, tpl *models.Template) (uint64, error)
+	UpdateTemplate(ctx context.Context, tpl *models.Template) error
+	DeleteTemplate(ctx context.Context, id uint64) error
+	SetTemplateCard(ctx context.Context, id uint64, card *models.Card) error
+	GetCard(ctx context.Context, id uint64) (*models.Card, bool, error)
+}
+
+type TemplateRepo struct {
+	db *sql.DB
+}
+
+func GetTemplateRepo(db *sql.DB) *TemplateRepo {
+	return &TemplateRepo{db: db}
+}
+
+func (r *TemplateRepo) GetTemplates(ctx context.Context) ([]*models.Template, error) {
+	templates := make([]*models.Template, 0)
+
+	rows, err := r.db.QueryContext(ctx, pkg.GetTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("get templates query error: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tpl := &models.Template{}
+
+		err := rows.Scan(&tpl.Id, &tpl.Name, &tpl.Locale, &tpl.Subject, &tpl.Body, &tpl.CardId)
+		if err != nil {
+			return nil, fmt.Errorf("get templates rows scan error: %s", err.Error())
+		}
+
+		templates = append(templates, tpl)
+	}
+
+	return templates, nil
+}
+
+func (r *TemplateRepo) GetTemplate(ctx context.Context, id uint64) (*models.Template, bool, error) {
+	tpl := &models.Template{}
+
+	err := r.db.QueryRowContext(ctx, pkg.GetTemplate, id).Scan(&tpl.Id, &tpl.Name, &tpl.Locale, &tpl.Subject, &tpl.Body, &tpl.CardId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("get template query error: %s", err.Error())
+	}
+
+	return tpl, true, nil
+}
+
+func (r *TemplateRepo) FindTemplate(ctx context.Context, name, locale string) (*models.Template, bool, error) {
+	tpl := &models.Template{}
+
+	err := r.db.QueryRowContext(ctx, pkg.FindTemplate, name, locale).Scan(&tpl.Id, &tpl.Name, &tpl.Locale, &tpl.Subject, &tpl.Body, &tpl.CardId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("find template query error: %s", err.Error())
+	}
+
+	return tpl, true, nil
+}
+
+func (r *TemplateRepo) CreateTemplate(ctx context.Context, tpl *models.Template) (uint64, error) {
+	var id uint64
+
+	err := r.db.QueryRowContext(ctx, pkg.CreateTemplate, tpl.Name, tpl.Locale, tpl.Subject, tpl.Body).Scan(&id)
+	if err != nil {
+		if utils.IsUniqueViolation(err) {
+			return 0, errs.ErrDuplicate
+		}
+		return 0, fmt.Errorf("create template error: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (r *TemplateRepo) UpdateTemplate(ctx context.Context, tpl *models.Template) error {
+	res, err := r.db.ExecContext(ctx, pkg.UpdateTemplate, tpl.Id, tpl.Name, tpl.Locale, tpl.Subject, tpl.Body)
+	if err != nil {
+		if utils.IsUniqueViolation(err) {
+			return errs.ErrDuplicate
+		}
+		return fmt.Errorf("update template error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *TemplateRepo) DeleteTemplate(ctx context.Context, id uint64) error {
+	res, err := r.db.ExecContext(ctx, pkg.DeleteTemplate, id)
+	if err != nil {
+		return fmt.Errorf("delete template error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return nil
+}
+
+// SetTemplateCard stores card and attaches it to the template. A nil card
+// detaches the current one. Old cards are kept for messages still queued.
+func (r *TemplateRepo) SetTemplateCard(ctx context.Context, id uint64, card *models.Card) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx error: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	var cardId uint64
+	if card != nil {
+		err = tx.QueryRowContext(ctx, pkg.CreateCard, card.ContentType, card.Data).Scan(&cardId)
+		if err != nil {
+			return fmt.Errorf("create card error: %s", err.Error())
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, pkg.SetTemplateCard, id, cardId)
+	if err != nil {
+		return fmt.Errorf("set template card error: %s", err.Error())
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %s", err.Error())
+	}
+
+	if rowsAffected == 0 {
+		return errs.ErrNotFound
+	}
+
+	return tx.Commit()
+}
+
+func (r *TemplateRepo) GetCard(ctx context.Context, id uint64) (*models.Card, bool, error) {
+	card := &models.Card{}
+
+	err := r.db.QueryRowContext(ctx, pkg.GetCard, id).Scan(&card.Id, &card.ContentType, &card.Data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("get card query error: %s", err.Error())
+	}
+
+	return card, true, nil
+}
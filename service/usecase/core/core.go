@@ -2,82 +2,166 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/robfig/cron"
 	"github.com/sirupsen/logrus"
+	"io"
+	"sort"
+	"strconv"
 	"time"
 	"vk-rest/configs"
 	utils "vk-rest/pkg"
+	"vk-rest/pkg/birthday"
+	errs "vk-rest/pkg/errors"
+	"vk-rest/pkg/export"
+	"vk-rest/pkg/ical"
 	"vk-rest/pkg/models"
+	"vk-rest/pkg/render"
+	"vk-rest/service/repository/feed"
 	"vk-rest/service/repository/profile"
 	"vk-rest/service/repository/session"
+	"vk-rest/service/repository/state"
 	"vk-rest/service/repository/sub"
+	"vk-rest/service/repository/team"
+	"vk-rest/service/repository/template"
 )
 
 //go:generate mockgen -source=core.go -destination=.mocks/core_mock.go -package=mocks
 type IProfileCore interface {
 	GetUserId(ctx context.Context, sid string) (uint64, error)
-	GetEmployees(ctx context.Context, offset, limit uint64) ([]*models.UserItem, error)
+	GetUserRole(ctx context.Context, userId uint64) (string, error)
+	SetUserRole(ctx context.Context, userId uint64, role string) error
+	GetEmployees(ctx context.Context, userId uint64, filter *models.EmployeeFilter) (*models.EmployeesPage, error)
+	GetEmployee(ctx context.Context, id uint64) (*models.UserItem, bool, error)
+	UpdateEmployee(ctx context.Context, id uint64, update *models.ProfileRequest) error
+	DeleteEmployee(ctx context.Context, id uint64) error
 	CreateUserAccount(ctx context.Context, user *models.SignupRequest) error
 	FindUserAccount(ctx context.Context, login string, password string) (*models.UserItem, bool, error)
 	FindUserByLogin(ctx context.Context, login string) (bool, error)
+	ImportEmployees(ctx context.Context, rows []*models.ImportRow, dryRun bool) (*models.ImportResult, error)
+	SetPasswordByToken(ctx context.Context, token, password string) (bool, error)
+	UpdateSettings(ctx context.Context, userId uint64, settings *models.SettingsRequest) error
+	GetChannels(ctx context.Context, userId uint64) ([]*models.Channel, error)
+	SetChannels(ctx context.Context, userId uint64, channels []*models.Channel) error
 }
 
 type ISessionCore interface {
 	GetUserName(ctx context.Context, sid string) (string, error)
-	CreateSession(ctx context.Context, login string) (models.Session, error)
+	CreateSession(ctx context.Context, login, ip, userAgent string) (models.Session, error)
 	FindActiveSession(ctx context.Context, sid string) (bool, error)
 	KillSession(ctx context.Context, sid string) error
+	GetSessions(ctx context.Context, sid string) ([]*models.SessionItem, error)
+	RevokeSession(ctx context.Context, sid, id string) error
+	RevokeAllSessions(ctx context.Context, sid string) error
 }
 
 type ISubCore interface {
-	BirthdaySub(ctx context.Context, userId, subscriberId uint64) (bool, error)
+	BirthdaySub(ctx context.Context, userId, subscriberId uint64, reminders []int) (bool, error)
+	UpdateReminders(ctx context.Context, userId, subscriberId uint64, reminders []int) error
 	BirthdayUnSub(ctx context.Context, userId, subscriberId uint64) (bool, error)
+	GetSubscriptions(ctx context.Context, userId, offset, limit uint64) (*models.EmployeesPage, error)
+	GetSubscribers(ctx context.Context, viewerId, userId, offset, limit uint64) (*models.EmployeesPage, error)
 }
 
-type Core struct {
-	log      *logrus.Logger
-	profiles profile.IProfileRepo
-	sessions session.ISessionRepo
-	subs     sub.ISubRepo
+type ITeamCore interface {
+	GetTeams(ctx context.Context, userId uint64) ([]*models.Team, error)
+	CreateTeam(ctx context.Context, name string) (uint64, error)
+	UpdateTeam(ctx context.Context, team *models.TeamRequest) error
+	DeleteTeam(ctx context.Context, id uint64) error
+	GetTeamMembers(ctx context.Context, teamId, offset, limit uint64) (*models.EmployeesPage, error)
+	AddTeamMember(ctx context.Context, teamId, userId uint64) error
+	RemoveTeamMember(ctx context.Context, teamId, userId uint64) error
+	TeamSub(ctx context.Context, teamId, userId uint64, reminders []int) error
+	UpdateTeamReminders(ctx context.Context, teamId, userId uint64, reminders []int) error
+	TeamUnSub(ctx context.Context, teamId, userId uint64) error
 }
 
-func GetCore(psxCfg *configs.DbPsxConfig, redisCfg *configs.DbRedisCfg, log *logrus.Logger) (*Core, error) {
-	profileRepo, err := profile.GetPsxRepo(psxCfg, log)
-	if err != nil {
-		log.Error("Get GetFilmRepo error: ", err)
-		return nil, err
-	}
+type IBirthdayCore interface {
+	GetUpcomingBirthdays(ctx context.Context, userId uint64, days int) ([]*models.UpcomingBirthday, error)
+	GetBirthdayCalendar(ctx context.Context, userId uint64, year int, month time.Month) ([]*models.UpcomingBirthday, error)
+}
+
+type IFeedCore interface {
+	CreateFeedToken(ctx context.Context, userId uint64) (string, error)
+	RevokeFeedToken(ctx context.Context, userId uint64) (bool, error)
+	GetFeed(ctx context.Context, token string) ([]byte, bool, error)
+}
+
+type IExportCore interface {
+	ExportEmployees(ctx context.Context, format string, out io.Writer) error
+	ExportSubscriptions(ctx context.Context, format string, out io.Writer) error
+}
+
+type ITemplateCore interface {
+	GetTemplates(ctx context.Context) ([]*models.Template, error)
+	CreateTemplate(ctx context.Context, tpl *models.Template) (uint64, error)
+	UpdateTemplate(ctx context.Context, tpl *models.Template) error
+	DeleteTemplate(ctx context.Context, id uint64) error
+	PreviewTemplate(ctx context.Context, request *models.PreviewRequest) (*models.RenderedMessage, error)
+	SetTemplateCard(ctx context.Context, id uint64, card *models.Card) error
+}
+
+type IWorkerCore interface {
+	GetWorkerState(ctx context.Context) (*models.WorkerState, error)
+	SetWorkerSchedule(ctx context.Context, schedule string) error
+	SetWorkerPaused(ctx context.Context, paused bool) error
+	TriggerWorker(ctx context.Context, date time.Time, dryRun bool) (*models.WorkerTrigger, error)
+	GetWorkerTrigger(ctx context.Context, id uint64) (*models.WorkerTrigger, bool, error)
+}
+
+// passwordTokenTTL is how long an imported employee may set a password.
+const passwordTokenTTL = 7 * 24 * time.Hour
+
+// dummyHash is checked for unknown logins, so they take as long as known ones.
+var dummyHash, _ = utils.HashPassword("")
 
+type Core struct {
+	log         *logrus.Logger
+	sessionCfg  *configs.SessionCfg
+	birthdayCfg *configs.BirthdayCfg
+	profiles    profile.IProfileRepo
+	sessions    session.ISessionRepo
+	subs        sub.ISubRepo
+	teams       team.ITeamRepo
+	templates   template.ITemplateRepo
+	feeds       feed.IFeedRepo
+	state       state.IStateRepo
+}
+
+func GetCore(db *sql.DB, redisCfg *configs.DbRedisCfg, sessionCfg *configs.SessionCfg, birthdayCfg *configs.BirthdayCfg, log *logrus.Logger) (*Core, error) {
 	authRepo, err := session.GetAuthRepo(redisCfg, log)
 	if err != nil {
 		log.Error("Get GetAuthRepo error: ", err)
 		return nil, err
 	}
 
-	subsRepo, err := sub.GetSubRepo(psxCfg, log)
-	if err != nil {
-		log.Error("Get GetSubRepo error: ", err)
-		return nil, err
-	}
-
 	core := &Core{
-		log:      log,
-		sessions: authRepo,
-		profiles: profileRepo,
-		subs:     subsRepo,
+		log:         log,
+		sessionCfg:  sessionCfg,
+		birthdayCfg: birthdayCfg,
+		sessions:    authRepo,
+		profiles:    profile.GetPsxRepo(db),
+		subs:        sub.GetSubRepo(db),
+		teams:       team.GetTeamRepo(db),
+		templates:   template.GetTemplateRepo(db),
+		feeds:       feed.GetFeedRepo(db),
+		state:       state.GetStateRepo(db),
 	}
 
 	return core, nil
 }
 
 func (c *Core) GetUserId(ctx context.Context, sid string) (uint64, error) {
-	login, err := c.sessions.GetUserLogin(ctx, sid)
+	active, err := c.activeSession(ctx, sid)
 	if err != nil {
 		c.log.Errorf("get user login error: %s", err.Error())
 		return 0, fmt.Errorf("get user login error: %s", err.Error())
 	}
 
-	id, err := c.profiles.GetUserId(ctx, login)
+	id, err := c.profiles.GetUserId(ctx, active.Login)
 	if err != nil {
 		c.log.Errorf("get user id error: %s", err.Error())
 		return 0, fmt.Errorf("get user id error: %s", err.Error())
@@ -86,24 +170,60 @@ func (c *Core) GetUserId(ctx context.Context, sid string) (uint64, error) {
 	return id, nil
 }
 
+func (c *Core) GetUserRole(ctx context.Context, userId uint64) (string, error) {
+	role, err := c.profiles.GetUserRole(ctx, userId)
+	if err != nil {
+		c.log.Errorf("get user role error: %s", err.Error())
+		return "", fmt.Errorf("get user role error: %s", err.Error())
+	}
+
+	return role, nil
+}
+
+func (c *Core) SetUserRole(ctx context.Context, userId uint64, role string) error {
+	err := c.profiles.UpdateRole(ctx, userId, role)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("set user role error: %s", err.Error())
+		return fmt.Errorf("set user role error: %s", err.Error())
+	}
+
+	return nil
+}
+
 func (c *Core) GetUserName(ctx context.Context, sid string) (string, error) {
-	login, err := c.sessions.GetUserLogin(ctx, sid)
+	active, err := c.activeSession(ctx, sid)
 	if err != nil {
 		c.log.Errorf("get user name error: %s", err.Error())
 		return "", fmt.Errorf("get user name error: %s", err.Error())
 	}
 
-	return login, nil
+	return active.Login, nil
 }
 
-func (c *Core) CreateSession(ctx context.Context, login string) (models.Session, error) {
+// CreateSession starts a session that lives for the idle timeout after each
+// use, but never longer than the maximum lifetime. ExpiresAt is the end of
+// the maximum lifetime.
+func (c *Core) CreateSession(ctx context.Context, login, ip, userAgent string) (models.Session, error) {
+	sid, err := utils.RandToken(32)
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	now := time.Now()
 	newSession := models.Session{
 		Login:     login,
-		SID:       utils.RandStringRunes(32),
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+		SID:       sid,
+		ExpiresAt: now.Add(c.sessionCfg.MaxLifetime),
+		CreatedAt: now,
+		LastSeen:  now,
+		IP:        ip,
+		UserAgent: userAgent,
 	}
 
-	sessionAdded, err := c.sessions.AddSession(ctx, newSession)
+	sessionAdded, err := c.sessions.AddSession(ctx, newSession, c.sessionCfg.IdleTimeout)
 	if !sessionAdded && err != nil {
 		return models.Session{}, err
 	}
@@ -116,13 +236,136 @@ func (c *Core) CreateSession(ctx context.Context, login string) (models.Session,
 }
 
 func (c *Core) FindActiveSession(ctx context.Context, sid string) (bool, error) {
-	login, err := c.sessions.CheckActiveSession(ctx, sid)
+	_, err := c.activeSession(ctx, sid)
 	if err != nil {
 		c.log.Errorf("find active session error: %s", err.Error())
 		return false, fmt.Errorf("find active session error: %s", err.Error())
 	}
 
-	return login, nil
+	return true, nil
+}
+
+// activeSession loads the session and extends it by the idle timeout,
+// capped by the maximum lifetime. Sessions past the maximum lifetime are
+// removed.
+func (c *Core) activeSession(ctx context.Context, sid string) (*models.Session, error) {
+	active, found, err := c.sessions.GetSession(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("session not found")
+	}
+
+	now := time.Now()
+	active.ExpiresAt = active.CreatedAt.Add(c.sessionCfg.MaxLifetime)
+
+	ttl := c.sessionCfg.IdleTimeout
+	if left := active.ExpiresAt.Sub(now); left < ttl {
+		ttl = left
+	}
+
+	if ttl <= 0 {
+		_, err = c.sessions.DeleteSession(ctx, sid)
+		if err != nil {
+			c.log.Errorf("delete expired session error: %s", err.Error())
+		}
+		return nil, fmt.Errorf("session expired")
+	}
+
+	err = c.sessions.TouchSession(ctx, sid, now, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	active.LastSeen = now
+	return active, nil
+}
+
+func (c *Core) GetSessions(ctx context.Context, sid string) ([]*models.SessionItem, error) {
+	login, err := c.sessions.GetUserLogin(ctx, sid)
+	if err != nil {
+		c.log.Errorf("get sessions error: %s", err.Error())
+		return nil, fmt.Errorf("get sessions error: %s", err.Error())
+	}
+
+	sessions, err := c.sessions.GetUserSessions(ctx, login)
+	if err != nil {
+		c.log.Errorf("get sessions error: %s", err.Error())
+		return nil, fmt.Errorf("get sessions error: %s", err.Error())
+	}
+
+	items := make([]*models.SessionItem, 0, len(sessions))
+	for _, userSession := range sessions {
+		items = append(items, &models.SessionItem{
+			Id:        utils.PublicId(userSession.SID),
+			CreatedAt: userSession.CreatedAt,
+			LastSeen:  userSession.LastSeen,
+			ExpiresAt: userSession.CreatedAt.Add(c.sessionCfg.MaxLifetime),
+			IP:        userSession.IP,
+			UserAgent: userSession.UserAgent,
+			Current:   userSession.SID == sid,
+		})
+	}
+
+	return items, nil
+}
+
+// RevokeSession ends the session of the same user whose public id is id.
+func (c *Core) RevokeSession(ctx context.Context, sid, id string) error {
+	login, err := c.sessions.GetUserLogin(ctx, sid)
+	if err != nil {
+		c.log.Errorf("revoke session error: %s", err.Error())
+		return fmt.Errorf("revoke session error: %s", err.Error())
+	}
+
+	sessions, err := c.sessions.GetUserSessions(ctx, login)
+	if err != nil {
+		c.log.Errorf("revoke session error: %s", err.Error())
+		return fmt.Errorf("revoke session error: %s", err.Error())
+	}
+
+	for _, userSession := range sessions {
+		if utils.PublicId(userSession.SID) != id {
+			continue
+		}
+
+		_, err = c.sessions.DeleteSession(ctx, userSession.SID)
+		if err != nil {
+			c.log.Errorf("revoke session error: %s", err.Error())
+			return fmt.Errorf("revoke session error: %s", err.Error())
+		}
+
+		return nil
+	}
+
+	return errs.ErrNotFound
+}
+
+// RevokeAllSessions ends every session of the user, the current one included.
+func (c *Core) RevokeAllSessions(ctx context.Context, sid string) error {
+	login, err := c.sessions.GetUserLogin(ctx, sid)
+	if err != nil {
+		c.log.Errorf("revoke sessions error: %s", err.Error())
+		return fmt.Errorf("revoke sessions error: %s", err.Error())
+	}
+
+	sessions, err := c.sessions.GetUserSessions(ctx, login)
+	if err != nil {
+		c.log.Errorf("revoke sessions error: %s", err.Error())
+		return fmt.Errorf("revoke sessions error: %s", err.Error())
+	}
+
+	for _, userSession := range sessions {
+		_, err = c.sessions.DeleteSession(ctx, userSession.SID)
+		if err != nil {
+			c.log.Errorf("revoke sessions error: %s", err.Error())
+			return fmt.Errorf("revoke sessions error: %s", err.Error())
+		}
+	}
+
+	return nil
 }
 
 func (c *Core) KillSession(ctx context.Context, sid string) error {
@@ -136,7 +379,13 @@ func (c *Core) KillSession(ctx context.Context, sid string) error {
 }
 
 func (c *Core) CreateUserAccount(ctx context.Context, user *models.SignupRequest) error {
-	err := c.profiles.CreateUser(ctx, user, utils.HashPassword(user.Password))
+	hash, err := utils.HashPassword(user.Password)
+	if err != nil {
+		c.log.Errorf("hash password error: %s", err.Error())
+		return fmt.Errorf("create user account error: %s", err.Error())
+	}
+
+	err = c.profiles.CreateUser(ctx, user, hash)
 	if err != nil {
 		c.log.Errorf("create user account error: %s", err.Error())
 		return fmt.Errorf("create user account error: %s", err.Error())
@@ -145,14 +394,40 @@ func (c *Core) CreateUserAccount(ctx context.Context, user *models.SignupRequest
 	return nil
 }
 
+// FindUserAccount checks the password against the stored hash. Hashes made
+// by an outdated algorithm are replaced after a successful check.
 func (c *Core) FindUserAccount(ctx context.Context, login string, password string) (*models.UserItem, bool, error) {
-	user, found, err := c.profiles.GetUser(ctx, login, utils.HashPassword(password))
+	user, hash, found, err := c.profiles.GetUser(ctx, login)
 	if err != nil {
 		c.log.Errorf("find user error: %s", err.Error())
 		return nil, false, fmt.Errorf("find user account error: %s", err.Error())
 	}
 
-	return user, found, nil
+	if !found {
+		// spend the same time as for an existing login
+		utils.CheckPassword(password, dummyHash)
+		return nil, false, nil
+	}
+
+	ok, rehash := utils.CheckPassword(password, hash)
+	if !ok {
+		return nil, false, nil
+	}
+
+	if rehash {
+		newHash, err := utils.HashPassword(password)
+		if err != nil {
+			c.log.Errorf("hash password error: %s", err.Error())
+			return user, true, nil
+		}
+
+		err = c.profiles.UpdatePassword(ctx, user.Id, newHash)
+		if err != nil {
+			c.log.Errorf("upgrade password hash error: %s", err.Error())
+		}
+	}
+
+	return user, true, nil
 }
 
 func (c *Core) FindUserByLogin(ctx context.Context, login string) (bool, error) {
@@ -165,17 +440,529 @@ func (c *Core) FindUserByLogin(ctx context.Context, login string) (bool, error)
 	return found, nil
 }
 
-func (c *Core) GetEmployees(ctx context.Context, limit, offset uint64) ([]*models.UserItem, error) {
-	users, err := c.profiles.GetEmployees(ctx, limit, offset)
+// ImportEmployees validates every row and reports the errors by row. When
+// there are none and dryRun is false, all rows are created in one
+// transaction and every employee gets a password-set token.
+func (c *Core) ImportEmployees(ctx context.Context, rows []*models.ImportRow, dryRun bool) (*models.ImportResult, error) {
+	result := &models.ImportResult{
+		DryRun:   dryRun,
+		Total:    len(rows),
+		Errors:   make([]*models.ImportRowError, 0),
+		Imported: make([]*models.ImportedEmployee, 0),
+	}
+
+	logins := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		message, err := c.validateImportRow(ctx, row, logins)
+		if err != nil {
+			c.log.Errorf("import employees error: %s", err.Error())
+			return nil, fmt.Errorf("import employees error: %s", err.Error())
+		}
+
+		if message != "" {
+			result.Errors = append(result.Errors, &models.ImportRowError{Row: row.Row, Login: row.Login, Error: message})
+		}
+		logins[row.Login] = true
+	}
+
+	if dryRun || len(result.Errors) != 0 {
+		return result, nil
+	}
+
+	expiresAt := time.Now().Add(passwordTokenTTL)
+	tokens := make([]string, 0, len(rows))
+	hashes := make([][]byte, 0, len(rows))
+	for range rows {
+		token, err := utils.RandToken(32)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := sha256.Sum256([]byte(token))
+		tokens = append(tokens, token)
+		hashes = append(hashes, hash[:])
+	}
+
+	ids, err := c.profiles.ImportUsers(ctx, rows, hashes, expiresAt)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) {
+			return nil, err
+		}
+		c.log.Errorf("import employees error: %s", err.Error())
+		return nil, fmt.Errorf("import employees error: %s", err.Error())
+	}
+
+	for i, row := range rows {
+		result.Imported = append(result.Imported, &models.ImportedEmployee{
+			Row:            row.Row,
+			Id:             ids[i],
+			Login:          row.Login,
+			PasswordToken:  tokens[i],
+			TokenExpiresAt: expiresAt,
+		})
+	}
+
+	return result, nil
+}
+
+// validateImportRow returns why the row cannot be imported, or "" when it
+// can. logins holds the logins of the rows before it.
+func (c *Core) validateImportRow(ctx context.Context, row *models.ImportRow, logins map[string]bool) (string, error) {
+	if row.Login == "" || row.Email == "" || row.Birthday == "" {
+		return errs.ErrMissingImportField, nil
+	}
+
+	if !utils.ValidEmail(row.Email) {
+		return errs.ErrBadEmail, nil
+	}
+
+	if !utils.ValidBirthday(row.Birthday) {
+		return errs.ErrBadBirthday, nil
+	}
+
+	if logins[row.Login] {
+		return errs.ErrRepeatedLogin, nil
+	}
+
+	found, err := c.profiles.FindUser(ctx, row.Login)
+	if err != nil {
+		return "", err
+	}
+
+	if found {
+		return errs.ErrAlreadyExists, nil
+	}
+
+	return "", nil
+}
+
+func (c *Core) SetPasswordByToken(ctx context.Context, token, password string) (bool, error) {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		c.log.Errorf("hash password error: %s", err.Error())
+		return false, fmt.Errorf("set password error: %s", err.Error())
+	}
+
+	tokenHash := sha256.Sum256([]byte(token))
+	found, err := c.profiles.UsePasswordToken(ctx, tokenHash[:], hash)
+	if err != nil {
+		c.log.Errorf("set password error: %s", err.Error())
+		return false, fmt.Errorf("set password error: %s", err.Error())
+	}
+
+	return found, nil
+}
+
+func (c *Core) UpdateSettings(ctx context.Context, userId uint64, settings *models.SettingsRequest) error {
+	if settings.Timezone != nil {
+		err := c.profiles.UpdateTimezone(ctx, userId, *settings.Timezone)
+		if err != nil {
+			c.log.Errorf("update timezone error: %s", err.Error())
+			return fmt.Errorf("update settings error: %s", err.Error())
+		}
+	}
+
+	if settings.Locale != nil {
+		err := c.profiles.UpdateLocale(ctx, userId, *settings.Locale)
+		if err != nil {
+			c.log.Errorf("update locale error: %s", err.Error())
+			return fmt.Errorf("update settings error: %s", err.Error())
+		}
+	}
+
+	if settings.HideSubscribers != nil {
+		err := c.profiles.UpdateHideSubscribers(ctx, userId, *settings.HideSubscribers)
+		if err != nil {
+			c.log.Errorf("update hide subscribers error: %s", err.Error())
+			return fmt.Errorf("update settings error: %s", err.Error())
+		}
+	}
+
+	if settings.DeliveryMode != nil {
+		err := c.profiles.UpdateDeliveryMode(ctx, userId, *settings.DeliveryMode)
+		if err != nil {
+			c.log.Errorf("update delivery mode error: %s", err.Error())
+			return fmt.Errorf("update settings error: %s", err.Error())
+		}
+	}
+
+	if settings.Visibility != nil {
+		err := c.profiles.UpdateBirthdayVisibility(ctx, userId, *settings.Visibility)
+		if err != nil {
+			c.log.Errorf("update birthday visibility error: %s", err.Error())
+			return fmt.Errorf("update settings error: %s", err.Error())
+		}
+	}
+
+	if settings.AllowSubscribe != nil {
+		err := c.profiles.UpdateAllowSubscribe(ctx, userId, *settings.AllowSubscribe)
+		if err != nil {
+			c.log.Errorf("update allow subscribe error: %s", err.Error())
+			return fmt.Errorf("update settings error: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (c *Core) GetChannels(ctx context.Context, userId uint64) ([]*models.Channel, error) {
+	channels, err := c.profiles.GetChannels(ctx, userId)
+	if err != nil {
+		c.log.Errorf("get channels error: %s", err.Error())
+		return nil, fmt.Errorf("get channels error: %s", err.Error())
+	}
+
+	return channels, nil
+}
+
+func (c *Core) SetChannels(ctx context.Context, userId uint64, channels []*models.Channel) error {
+	err := c.profiles.SetChannels(ctx, userId, channels)
+	if err != nil {
+		c.log.Errorf("set channels error: %s", err.Error())
+		return fmt.Errorf("set channels error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// GetEmployees returns a page of employees. The upcoming sort starts from
+// today in the time zone of the user.
+func (c *Core) GetEmployees(ctx context.Context, userId uint64, filter *models.EmployeeFilter) (*models.EmployeesPage, error) {
+	if filter.Sort == models.SortByUpcoming {
+		today, err := c.userToday(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.Today = today.Format("01-02")
+		filter.LeapDayKey = upcomingKey(time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC), today, c.birthdayCfg.LeapDayPolicy)
+	}
+
+	page, next, err := c.profiles.SearchEmployees(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	hideBirthdays(page)
+
+	if next != nil {
+		page.NextCursor, err = utils.EncodeCursor(next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// upcomingKey returns the upcoming sort key of born: the number of years until
+// the next birthday, at most 2, followed by its MM-DD day.
+func upcomingKey(born, today time.Time, policy birthday.LeapDayPolicy) string {
+	next := birthday.Next(born, today, policy)
+	years := next.Year() - today.Year()
+	if years > 2 {
+		years = 2
+	}
+
+	return strconv.Itoa(years) + next.Format("01-02")
+}
+
+// hideBirthdays applies the birthday visibility of every employee of page.
+func hideBirthdays(page *models.EmployeesPage) {
+	for _, item := range page.Items {
+		item.HideBirthday()
+	}
+}
+
+func (c *Core) GetEmployee(ctx context.Context, id uint64) (*models.UserItem, bool, error) {
+	user, found, err := c.profiles.GetEmployee(ctx, id)
+	if err != nil {
+		c.log.Errorf("get employee error: %s", err.Error())
+		return nil, false, fmt.Errorf("get employee error: %s", err.Error())
+	}
+
+	return user, found, nil
+}
+
+func (c *Core) UpdateEmployee(ctx context.Context, id uint64, update *models.ProfileRequest) error {
+	err := c.profiles.UpdateEmployee(ctx, id, update)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("update employee error: %s", err.Error())
+		return fmt.Errorf("update employee error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteEmployee removes the profile and ends all of its sessions.
+func (c *Core) DeleteEmployee(ctx context.Context, id uint64) error {
+	user, found, err := c.profiles.GetEmployee(ctx, id)
+	if err != nil {
+		c.log.Errorf("delete employee error: %s", err.Error())
+		return fmt.Errorf("delete employee error: %s", err.Error())
+	}
+
+	if !found {
+		return errs.ErrNotFound
+	}
+
+	err = c.profiles.DeleteEmployee(ctx, id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("delete employee error: %s", err.Error())
+		return fmt.Errorf("delete employee error: %s", err.Error())
+	}
+
+	sessions, err := c.sessions.GetUserSessions(ctx, user.Login)
+	if err != nil {
+		c.log.Errorf("get sessions of deleted employee error: %s", err.Error())
+		return nil
+	}
+
+	for _, userSession := range sessions {
+		_, err = c.sessions.DeleteSession(ctx, userSession.SID)
+		if err != nil {
+			c.log.Errorf("delete session of deleted employee error: %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// GetUpcomingBirthdays returns the birthdays from today to days ahead, today
+// being the date in the time zone of the user.
+func (c *Core) GetUpcomingBirthdays(ctx context.Context, userId uint64, days int) ([]*models.UpcomingBirthday, error) {
+	today, err := c.userToday(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
 
-	return users, nil
+	return c.birthdaysBetween(ctx, userId, today, today, today.AddDate(0, 0, days))
+}
+
+// GetBirthdayCalendar returns the birthdays in month of year.
+func (c *Core) GetBirthdayCalendar(ctx context.Context, userId uint64, year int, month time.Month) ([]*models.UpcomingBirthday, error) {
+	today, err := c.userToday(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return c.birthdaysBetween(ctx, userId, today, first, first.AddDate(0, 1, -1))
+}
+
+func (c *Core) userToday(ctx context.Context, userId uint64) (time.Time, error) {
+	user, found, err := c.profiles.GetEmployee(ctx, userId)
+	if err != nil {
+		c.log.Errorf("get employee error: %s", err.Error())
+		return time.Time{}, fmt.Errorf("get employee error: %s", err.Error())
+	}
+
+	loc := time.UTC
+	if found {
+		if userLoc, err := time.LoadLocation(user.Timezone); err == nil {
+			loc = userLoc
+		}
+	}
+
+	return birthday.Date(time.Now().In(loc)), nil
 }
 
-func (c *Core) BirthdaySub(ctx context.Context, userId, subscriberId uint64) (bool, error) {
-	res, err := c.subs.BirthdaySub(ctx, userId, subscriberId)
+// birthdaysBetween returns the birthdays from first to last ordered by date.
+// The database is asked for one more day on both sides, because February 29
+// may be celebrated on February 28 or March 1 in common years.
+func (c *Core) birthdaysBetween(ctx context.Context, userId uint64, today, first, last time.Time) ([]*models.UpcomingBirthday, error) {
+	from, to := "01-01", "12-31"
+	if birthday.DaysUntil(first, last) < 362 {
+		from, to = first.AddDate(0, 0, -1).Format("01-02"), last.AddDate(0, 0, 1).Format("01-02")
+	}
+
+	items, err := c.profiles.GetBirthdaysBetween(ctx, userId, from, to)
+	if err != nil {
+		c.log.Errorf("get birthdays error: %s", err.Error())
+		return nil, fmt.Errorf("get birthdays error: %s", err.Error())
+	}
+
+	birthdays := make([]*models.UpcomingBirthday, 0, len(items))
+	for _, item := range items {
+		born, err := utils.ParseDate(item.Birthday)
+		if err != nil {
+			c.log.Errorf("parse birthday of %d error: %s", item.Id, err.Error())
+			continue
+		}
+
+		date := birthday.Next(born, first, c.birthdayCfg.LeapDayPolicy)
+		if date.IsZero() || date.After(last) {
+			continue
+		}
+
+		item.Date = date.Format(time.DateOnly)
+		item.Age = birthday.Age(born, date)
+		item.DaysUntil = birthday.DaysUntil(today, date)
+		if item.Id != userId {
+			if item.Visibility == models.VisibilityNoYear {
+				item.Age = 0
+			}
+			item.HideBirthday()
+		}
+		birthdays = append(birthdays, item)
+	}
+
+	sort.SliceStable(birthdays, func(i, j int) bool {
+		if birthdays[i].Date != birthdays[j].Date {
+			return birthdays[i].Date < birthdays[j].Date
+		}
+		return birthdays[i].Login < birthdays[j].Login
+	})
+
+	return birthdays, nil
+}
+
+// CreateFeedToken issues a new calendar feed token for the user. Only its
+// hash is stored, so the previous token stops working.
+func (c *Core) CreateFeedToken(ctx context.Context, userId uint64) (string, error) {
+	token, err := utils.RandToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256([]byte(token))
+	err = c.feeds.SetToken(ctx, userId, hash[:])
+	if err != nil {
+		c.log.Errorf("create feed token error: %s", err.Error())
+		return "", fmt.Errorf("create feed token error: %s", err.Error())
+	}
+
+	return token, nil
+}
+
+func (c *Core) RevokeFeedToken(ctx context.Context, userId uint64) (bool, error) {
+	deleted, err := c.feeds.DeleteToken(ctx, userId)
+	if err != nil {
+		c.log.Errorf("revoke feed token error: %s", err.Error())
+		return false, fmt.Errorf("revoke feed token error: %s", err.Error())
+	}
+
+	return deleted, nil
+}
+
+// GetFeed renders the iCalendar feed of the owner of token.
+func (c *Core) GetFeed(ctx context.Context, token string) ([]byte, bool, error) {
+	hash := sha256.Sum256([]byte(token))
+	userId, found, err := c.feeds.GetTokenOwner(ctx, hash[:])
+	if err != nil {
+		c.log.Errorf("get feed error: %s", err.Error())
+		return nil, false, fmt.Errorf("get feed error: %s", err.Error())
+	}
+
+	if !found {
+		return nil, false, nil
+	}
+
+	entries, err := c.feeds.GetEntries(ctx, userId)
+	if err != nil {
+		c.log.Errorf("get feed error: %s", err.Error())
+		return nil, false, fmt.Errorf("get feed error: %s", err.Error())
+	}
+
+	events := make([]*ical.Event, 0, len(entries))
+	for _, entry := range entries {
+		born, err := utils.ParseDate(entry.Birthday)
+		if err != nil {
+			c.log.Errorf("parse birthday of %d error: %s", entry.Id, err.Error())
+			continue
+		}
+
+		// a leap year keeps February 29 valid
+		if entry.Visibility == models.VisibilityNoYear {
+			born = time.Date(2000, born.Month(), born.Day(), 0, 0, 0, 0, time.UTC)
+		}
+
+		events = append(events, &ical.Event{
+			UID:     fmt.Sprintf("birthday-%d@vk-rest", entry.Id),
+			Summary: "День рождения: " + entry.Login,
+			Start:   born,
+			Rule:    ical.YearlyRule(born, c.birthdayCfg.LeapDayPolicy),
+			Stamp:   entry.ChangedAt,
+		})
+	}
+
+	return ical.Calendar("Дни рождения", events), true, nil
+}
+
+var employeeColumns = []string{"id", "login", "email", "birthday", "next_birthday", "timezone", "locale", "department", "role"}
+
+var subscriptionColumns = []string{"subscriber_id", "subscriber_login", "employee_id", "employee_login", "created_at"}
+
+// ExportEmployees writes all profiles to out as they are read. next_birthday
+// follows the leap day policy. Password hashes are not exported.
+func (c *Core) ExportEmployees(ctx context.Context, format string, out io.Writer) error {
+	table, err := export.NewWriter(format, out, "employees", employeeColumns)
+	if err != nil {
+		return fmt.Errorf("export employees error: %s", err.Error())
+	}
+
+	today := time.Now()
+	err = c.profiles.StreamEmployees(ctx, func(user *models.UserItem) error {
+		born, next := user.Birthday, ""
+		if date, err := utils.ParseDate(user.Birthday); err == nil {
+			born = date.Format(time.DateOnly)
+			next = birthday.Next(date, today, c.birthdayCfg.LeapDayPolicy).Format(time.DateOnly)
+		}
+
+		return table.WriteRow([]any{user.Id, user.Login, user.Email, born, next,
+			user.Timezone, user.Locale, user.Department, user.Role})
+	})
+	if err != nil {
+		c.log.Errorf("export employees error: %s", err.Error())
+		return fmt.Errorf("export employees error: %s", err.Error())
+	}
+
+	return table.Close()
+}
+
+func (c *Core) ExportSubscriptions(ctx context.Context, format string, out io.Writer) error {
+	table, err := export.NewWriter(format, out, "subscriptions", subscriptionColumns)
+	if err != nil {
+		return fmt.Errorf("export subscriptions error: %s", err.Error())
+	}
+
+	err = c.profiles.StreamSubscriptions(ctx, func(subscription *models.Subscription) error {
+		return table.WriteRow([]any{subscription.SubscriberId, subscription.SubscriberLogin,
+			subscription.EmployeeId, subscription.EmployeeLogin, subscription.CreatedAt.UTC().Format(time.RFC3339)})
+	})
+	if err != nil {
+		c.log.Errorf("export subscriptions error: %s", err.Error())
+		return fmt.Errorf("export subscriptions error: %s", err.Error())
+	}
+
+	return table.Close()
+}
+
+// BirthdaySub subscribes userId to subscriberId with reminders the given
+// numbers of days before the birthday. Employees who hide their birthday or
+// refuse subscriptions cannot be subscribed to.
+func (c *Core) BirthdaySub(ctx context.Context, userId, subscriberId uint64, reminders []int) (bool, error) {
+	visibility, allow, found, err := c.profiles.GetPrivacy(ctx, subscriberId)
+	if err != nil {
+		c.log.Errorf("birthday sub error: %s", err.Error())
+		return false, fmt.Errorf("birthday sub error: %s", err.Error())
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	if !allow || visibility == models.VisibilityHidden {
+		return false, errs.ErrSubscriptionRefused
+	}
+
+	res, err := c.subs.BirthdaySub(ctx, userId, subscriberId, reminders)
 	if err != nil {
 		return false, err
 	}
@@ -183,6 +970,19 @@ func (c *Core) BirthdaySub(ctx context.Context, userId, subscriberId uint64) (bo
 	return res, nil
 }
 
+func (c *Core) UpdateReminders(ctx context.Context, userId, subscriberId uint64, reminders []int) error {
+	err := c.subs.UpdateReminders(ctx, userId, subscriberId, reminders)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("update reminders error: %s", err.Error())
+		return fmt.Errorf("update reminders error: %s", err.Error())
+	}
+
+	return nil
+}
+
 func (c *Core) BirthdayUnSub(ctx context.Context, userId, subscriberId uint64) (bool, error) {
 	res, err := c.subs.BirthdayUnSub(ctx, userId, subscriberId)
 	if err != nil {
@@ -191,3 +991,415 @@ func (c *Core) BirthdayUnSub(ctx context.Context, userId, subscriberId uint64) (
 
 	return res, nil
 }
+
+func (c *Core) GetSubscriptions(ctx context.Context, userId, offset, limit uint64) (*models.EmployeesPage, error) {
+	page, err := c.subs.GetEmployeesBySubId(ctx, userId, offset, limit)
+	if err != nil {
+		c.log.Errorf("get subscriptions error: %s", err.Error())
+		return nil, fmt.Errorf("get subscriptions error: %s", err.Error())
+	}
+
+	hideBirthdays(page)
+
+	return page, nil
+}
+
+// GetSubscribers returns who is subscribed to userId. Employees who hide
+// their subscribers show them only to themselves and to those who manage
+// employees.
+func (c *Core) GetSubscribers(ctx context.Context, viewerId, userId, offset, limit uint64) (*models.EmployeesPage, error) {
+	if viewerId != userId {
+		hidden, found, err := c.profiles.GetHideSubscribers(ctx, userId)
+		if err != nil {
+			c.log.Errorf("get subscribers error: %s", err.Error())
+			return nil, fmt.Errorf("get subscribers error: %s", err.Error())
+		}
+
+		if !found {
+			return nil, errs.ErrNotFound
+		}
+
+		if hidden {
+			role, err := c.GetUserRole(ctx, viewerId)
+			if err != nil {
+				return nil, err
+			}
+
+			if !models.HasPermission(role, models.PermManageEmployees) {
+				return nil, errs.ErrHidden
+			}
+		}
+	}
+
+	page, err := c.subs.GetSubscribers(ctx, userId, offset, limit)
+	if err != nil {
+		c.log.Errorf("get subscribers error: %s", err.Error())
+		return nil, fmt.Errorf("get subscribers error: %s", err.Error())
+	}
+
+	hideBirthdays(page)
+
+	return page, nil
+}
+
+func (c *Core) GetTeams(ctx context.Context, userId uint64) ([]*models.Team, error) {
+	teams, err := c.teams.GetTeams(ctx, userId)
+	if err != nil {
+		c.log.Errorf("get teams error: %s", err.Error())
+		return nil, fmt.Errorf("get teams error: %s", err.Error())
+	}
+
+	return teams, nil
+}
+
+func (c *Core) CreateTeam(ctx context.Context, name string) (uint64, error) {
+	id, err := c.teams.CreateTeam(ctx, name)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) {
+			return 0, err
+		}
+		c.log.Errorf("create team error: %s", err.Error())
+		return 0, fmt.Errorf("create team error: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (c *Core) UpdateTeam(ctx context.Context, team *models.TeamRequest) error {
+	err := c.teams.UpdateTeam(ctx, team)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) || errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("update team error: %s", err.Error())
+		return fmt.Errorf("update team error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteTeam removes the team with its memberships and subscriptions. The
+// members themselves are kept.
+func (c *Core) DeleteTeam(ctx context.Context, id uint64) error {
+	err := c.teams.DeleteTeam(ctx, id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("delete team error: %s", err.Error())
+		return fmt.Errorf("delete team error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *Core) GetTeamMembers(ctx context.Context, teamId, offset, limit uint64) (*models.EmployeesPage, error) {
+	page, found, err := c.teams.GetMembers(ctx, teamId, offset, limit)
+	if err != nil {
+		c.log.Errorf("get team members error: %s", err.Error())
+		return nil, fmt.Errorf("get team members error: %s", err.Error())
+	}
+
+	if !found {
+		return nil, errs.ErrNotFound
+	}
+
+	hideBirthdays(page)
+
+	return page, nil
+}
+
+func (c *Core) AddTeamMember(ctx context.Context, teamId, userId uint64) error {
+	err := c.teams.AddMember(ctx, teamId, userId)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("add team member error: %s", err.Error())
+		return fmt.Errorf("add team member error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *Core) RemoveTeamMember(ctx context.Context, teamId, userId uint64) error {
+	err := c.teams.RemoveMember(ctx, teamId, userId)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("remove team member error: %s", err.Error())
+		return fmt.Errorf("remove team member error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// TeamSub subscribes userId to the birthdays of everyone who is or will be
+// a member of teamId.
+func (c *Core) TeamSub(ctx context.Context, teamId, userId uint64, reminders []int) error {
+	err := c.teams.Subscribe(ctx, teamId, userId, reminders)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) || errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("team subscribe error: %s", err.Error())
+		return fmt.Errorf("team subscribe error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *Core) UpdateTeamReminders(ctx context.Context, teamId, userId uint64, reminders []int) error {
+	err := c.teams.UpdateReminders(ctx, teamId, userId, reminders)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("update team reminders error: %s", err.Error())
+		return fmt.Errorf("update team reminders error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *Core) TeamUnSub(ctx context.Context, teamId, userId uint64) error {
+	err := c.teams.Unsubscribe(ctx, teamId, userId)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("team unsubscribe error: %s", err.Error())
+		return fmt.Errorf("team unsubscribe error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *Core) GetTemplates(ctx context.Context) ([]*models.Template, error) {
+	templates, err := c.templates.GetTemplates(ctx)
+	if err != nil {
+		c.log.Errorf("get templates error: %s", err.Error())
+		return nil, fmt.Errorf("get templates error: %s", err.Error())
+	}
+
+	return templates, nil
+}
+
+func (c *Core) CreateTemplate(ctx context.Context, tpl *models.Template) (uint64, error) {
+	err := render.Validate(tpl)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", errs.ErrTemplate, err.Error())
+	}
+
+	id, err := c.templates.CreateTemplate(ctx, tpl)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) {
+			return 0, err
+		}
+		c.log.Errorf("create template error: %s", err.Error())
+		return 0, fmt.Errorf("create template error: %s", err.Error())
+	}
+
+	return id, nil
+}
+
+func (c *Core) UpdateTemplate(ctx context.Context, tpl *models.Template) error {
+	err := render.Validate(tpl)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errs.ErrTemplate, err.Error())
+	}
+
+	err = c.templates.UpdateTemplate(ctx, tpl)
+	if err != nil {
+		if errors.Is(err, errs.ErrDuplicate) || errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("update template error: %s", err.Error())
+		return fmt.Errorf("update template error: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (c *Core) DeleteTemplate(ctx context.Context, id uint64) error {
+	err := c.templates.DeleteTemplate(ctx, id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("delete template error: %s", err.Error())
+		return fmt.Errorf("delete template error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// PreviewTemplate renders a stored template, or the subject and body from
+// the request when no id is given. Missing data is replaced by sample values.
+func (c *Core) PreviewTemplate(ctx context.Context, request *models.PreviewRequest) (*models.RenderedMessage, error) {
+	tpl := &models.Template{Subject: request.Subject, Body: request.Body}
+
+	if request.Id != 0 {
+		stored, found, err := c.templates.GetTemplate(ctx, request.Id)
+		if err != nil {
+			c.log.Errorf("get template error: %s", err.Error())
+			return nil, fmt.Errorf("preview template error: %s", err.Error())
+		}
+
+		if !found {
+			return nil, errs.ErrNotFound
+		}
+
+		tpl = stored
+	}
+
+	data := request.Data
+	if data == nil {
+		data = render.SampleData()
+	}
+
+	rendered, err := render.Render(tpl, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrTemplate, err.Error())
+	}
+
+	return rendered, nil
+}
+
+// SetTemplateCard attaches card to the template, or detaches the current one
+// when card is nil.
+func (c *Core) SetTemplateCard(ctx context.Context, id uint64, card *models.Card) error {
+	err := c.templates.SetTemplateCard(ctx, id, card)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return err
+		}
+		c.log.Errorf("set template card error: %s", err.Error())
+		return fmt.Errorf("set template card error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// GetWorkerState returns the schedule of the birthday job and, unless it is
+// paused, the time of its next run.
+func (c *Core) GetWorkerState(ctx context.Context) (*models.WorkerState, error) {
+	workerState, err := c.state.GetWorkerState(ctx)
+	if err != nil {
+		c.log.Errorf("get worker state error: %s", err.Error())
+		return nil, fmt.Errorf("get worker state error: %s", err.Error())
+	}
+
+	if !workerState.Paused {
+		schedule, err := cron.Parse(workerState.Schedule)
+		if err != nil {
+			c.log.Errorf("parse worker schedule error: %s", err.Error())
+			return workerState, nil
+		}
+
+		next := schedule.Next(time.Now())
+		workerState.NextRun = &next
+	}
+
+	return workerState, nil
+}
+
+// SetWorkerSchedule stores a new cron expression for the birthday job. The
+// running worker picks it up on its next state check. The job must run at
+// least once an hour, or greetings would come long after 08:00 in some time
+// zones.
+func (c *Core) SetWorkerSchedule(ctx context.Context, schedule string) error {
+	parsed, err := cron.Parse(schedule)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errs.ErrSchedule, err.Error())
+	}
+
+	if !runsHourly(parsed, time.Now(), time.Now().AddDate(1, 0, 0)) {
+		return fmt.Errorf("%w: the job must run at least once an hour", errs.ErrSchedule)
+	}
+
+	err = c.state.UpdateSchedule(ctx, schedule)
+	if err != nil {
+		c.log.Errorf("set worker schedule error: %s", err.Error())
+		return fmt.Errorf("set worker schedule error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// runsHourly reports whether schedule runs at least once in every hour from
+// from to until. Within an hour the runs follow the same pattern, so only
+// the gap after the last run of every hour with runs is checked.
+func runsHourly(schedule cron.Schedule, from, until time.Time) bool {
+	if every, ok := schedule.(cron.ConstantDelaySchedule); ok {
+		return every.Delay <= time.Hour
+	}
+
+	first := schedule.Next(from)
+	if first.IsZero() || first.Sub(from) > time.Hour {
+		return false
+	}
+
+	hour := startOfHour(first)
+	last := first
+	for next := schedule.Next(last); !next.IsZero() && next.Before(hour.Add(time.Hour)); next = schedule.Next(next) {
+		last = next
+	}
+	offset := last.Sub(hour)
+
+	for run := last; run.Before(until); {
+		next := schedule.Next(run)
+		if next.IsZero() || next.Sub(run) > time.Hour {
+			return false
+		}
+
+		run = next
+		if last := startOfHour(next).Add(offset); last.After(next) {
+			run = last
+		}
+	}
+
+	return true
+}
+
+func startOfHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+func (c *Core) SetWorkerPaused(ctx context.Context, paused bool) error {
+	err := c.state.UpdatePaused(ctx, paused)
+	if err != nil {
+		c.log.Errorf("set worker paused error: %s", err.Error())
+		return fmt.Errorf("set worker paused error: %s", err.Error())
+	}
+
+	return nil
+}
+
+// TriggerWorker records a manual run of the birthday job for date. The
+// worker does it on its next tick, under the worker lease.
+func (c *Core) TriggerWorker(ctx context.Context, date time.Time, dryRun bool) (*models.WorkerTrigger, error) {
+	id, err := c.state.AddTrigger(ctx, date, dryRun)
+	if err != nil {
+		c.log.Errorf("trigger worker error: %s", err.Error())
+		return nil, fmt.Errorf("trigger worker error: %s", err.Error())
+	}
+
+	trigger, _, err := c.GetWorkerTrigger(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return trigger, nil
+}
+
+func (c *Core) GetWorkerTrigger(ctx context.Context, id uint64) (*models.WorkerTrigger, bool, error) {
+	trigger, found, err := c.state.GetTrigger(ctx, id)
+	if err != nil {
+		c.log.Errorf("get worker trigger error: %s", err.Error())
+		return nil, false, fmt.Errorf("get worker trigger error: %s", err.Error())
+	}
+
+	return trigger, found, nil
+}
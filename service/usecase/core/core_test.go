@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"github.com/robfig/cron"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"vk-rest/configs"
+	"vk-rest/pkg/birthday"
+	"vk-rest/pkg/models"
+	pkg "vk-rest/pkg/sql"
+	"vk-rest/service/repository/profile"
+)
+
+type fakeProfileRepo struct {
+	profile.IProfileRepo
+	birthdays []*models.UpcomingBirthday
+}
+
+func (r *fakeProfileRepo) GetEmployee(ctx context.Context, id uint64) (*models.UserItem, bool, error) {
+	return &models.UserItem{Id: id, Timezone: "UTC"}, true, nil
+}
+
+func (r *fakeProfileRepo) GetBirthdaysBetween(ctx context.Context, subscriberId uint64, from, to string) ([]*models.UpcomingBirthday, error) {
+	return r.birthdays, nil
+}
+
+func testCore(profiles profile.IProfileRepo) *Core {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	return &Core{
+		log:         log,
+		birthdayCfg: &configs.BirthdayCfg{LeapDayPolicy: birthday.LeapDayFeb28},
+		profiles:    profiles,
+	}
+}
+
+func TestUpcomingKey(t *testing.T) {
+	leapBorn := time.Date(1996, time.February, 29, 0, 0, 0, 0, time.UTC)
+	born := time.Date(1990, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		born   time.Time
+		today  time.Time
+		policy birthday.LeapDayPolicy
+		want   string
+	}{
+		{born, time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC), birthday.LeapDayFeb28, "003-01"},
+		{born, time.Date(2023, time.March, 2, 0, 0, 0, 0, time.UTC), birthday.LeapDayFeb28, "103-01"},
+		{leapBorn, time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC), birthday.LeapDayFeb28, "002-28"},
+		{leapBorn, time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC), birthday.LeapDayMar1, "003-01"},
+		{leapBorn, time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC), birthday.LeapDayFeb28, "102-29"},
+		{leapBorn, time.Date(2023, time.January, 10, 0, 0, 0, 0, time.UTC), birthday.LeapDaySkip, "102-29"},
+		{leapBorn, time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC), birthday.LeapDaySkip, "002-29"},
+		{leapBorn, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), birthday.LeapDaySkip, "202-29"},
+		{leapBorn, time.Date(2100, time.January, 10, 0, 0, 0, 0, time.UTC), birthday.LeapDayFeb28, "002-28"},
+		{leapBorn, time.Date(2100, time.January, 10, 0, 0, 0, 0, time.UTC), birthday.LeapDaySkip, "202-29"},
+	}
+
+	for _, tt := range tests {
+		got := upcomingKey(tt.born, tt.today, tt.policy)
+		if got != tt.want {
+			t.Errorf("upcomingKey(%s, %s, %s) = %s, want %s", tt.born.Format(time.DateOnly), tt.today.Format(time.DateOnly), tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestRunsHourly(t *testing.T) {
+	from := time.Date(2023, time.January, 10, 12, 34, 56, 0, time.Local)
+
+	tests := []struct {
+		schedule string
+		want     bool
+	}{
+		{"0 */15 * * * *", true},
+		{"0 0 * * * *", true},
+		{"59 59 * * * *", true},
+		{"*/10 * * * * *", true},
+		{"0 0,30 0-23 * * *", true},
+		{"@every 1h", true},
+		{"@every 10m", true},
+		{"0 0 */2 * * *", false},
+		{"0 0 8 * * *", false},
+		{"0 */15 0-22 * * *", false},
+		{"59 59 0-2,4-23 * * *", false},
+		{"0 * * * * 1-5", false},
+		{"0 * * 1-15 * *", false},
+		{"0 0 * 29 2 *", false},
+		{"@every 61m", false},
+		{"@daily", false},
+	}
+
+	for _, tt := range tests {
+		schedule, err := cron.Parse(tt.schedule)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tt.schedule, err)
+		}
+
+		got := runsHourly(schedule, from, from.AddDate(1, 0, 0))
+		if got != tt.want {
+			t.Errorf("runsHourly(%s) = %t, want %t", tt.schedule, got, tt.want)
+		}
+	}
+}
+
+func TestBirthdaysTeamSubscription(t *testing.T) {
+	// the flag must match who the feed and the notifications reach
+	for _, clause := range []string{"p.allow_subscribe AND", "team_subscriber ts JOIN team_member tm"} {
+		if !strings.Contains(pkg.GetBirthdaysBetween, clause) {
+			t.Errorf("GetBirthdaysBetween does not contain %q", clause)
+		}
+	}
+
+	// colleague 2 is reached only through a team the user subscribed to
+	repo := &fakeProfileRepo{birthdays: []*models.UpcomingBirthday{
+		{UserItem: models.UserItem{Id: 2, Login: "team", Birthday: "1990-06-15", Visibility: models.VisibilityNoYear}, Subscribed: true},
+		{UserItem: models.UserItem{Id: 3, Login: "other", Birthday: "1991-06-20", Visibility: models.VisibilityFull}},
+	}}
+
+	got, err := testCore(repo).GetBirthdayCalendar(context.Background(), 1, 2030, time.June)
+	if err != nil {
+		t.Fatalf("GetBirthdayCalendar: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("GetBirthdayCalendar returned %d birthdays, want 2", len(got))
+	}
+	if got[0].Id != 2 || !got[0].Subscribed || got[0].Birthday != "--06-15" || got[0].Age != 0 {
+		t.Errorf("team colleague = %+v, want subscribed with the year hidden", got[0])
+	}
+	if got[1].Id != 3 || got[1].Subscribed {
+		t.Errorf("other colleague = %+v, want not subscribed", got[1])
+	}
+}
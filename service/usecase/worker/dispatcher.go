@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vk-rest/pkg/models"
+)
+
+const (
+	dispatchBatch      = 50
+	dispatchLease      = 5 * time.Minute
+	dispatchAttempts   = 8
+	dispatchBackoff    = time.Minute
+	dispatchMaxBackoff = 6 * time.Hour
+)
+
+// Dispatch delivers due outbox messages. A failed message is retried with
+// exponential backoff until dispatchAttempts is reached, after which it is
+// left in the failed state for manual inspection.
+func (w *Worker) Dispatch() {
+	ctx := context.Background()
+
+	messages, err := w.outbox.ClaimPending(ctx, w.now(), dispatchLease, dispatchBatch)
+	if err != nil {
+		w.log.Errorf("Error in ClaimPending: %v", err)
+		return
+	}
+
+	cards := make(map[uint64]*models.Card)
+
+	for _, msg := range messages {
+		mail := &models.Mail{
+			To:      msg.To,
+			Subject: msg.Subject,
+			Body:    msg.Body,
+			HTML:    msg.HTML,
+		}
+
+		if msg.CardId != 0 {
+			mail.Card, err = w.card(ctx, cards, msg.CardId)
+			if err != nil {
+				w.log.Errorf("Error in GetCard %d: %v", msg.CardId, err)
+			}
+		}
+
+		var sendErr error
+		notifier, ok := w.notifiers[msg.Channel]
+		if ok {
+			sendErr = notifier.Notify(ctx, mail)
+		} else {
+			sendErr = fmt.Errorf("channel %s is not configured", msg.Channel)
+		}
+		now := w.now()
+
+		if sendErr == nil {
+			err = w.outbox.MarkSent(ctx, msg.Id, now)
+			if err != nil {
+				w.log.Errorf("Error in MarkSent %d: %v", msg.Id, err)
+			}
+			continue
+		}
+
+		attempts := msg.Attempts + 1
+		final := attempts >= dispatchAttempts
+		w.log.Errorf("Error in Notify %d (attempt %d): %v", msg.Id, attempts, sendErr)
+
+		err = w.outbox.MarkFailed(ctx, msg.Id, now, sendErr.Error(), now.Add(backoff(attempts)), final)
+		if err != nil {
+			w.log.Errorf("Error in MarkFailed %d: %v", msg.Id, err)
+		}
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	delay := dispatchBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= dispatchMaxBackoff {
+			return dispatchMaxBackoff
+		}
+	}
+
+	return delay
+}
+
+// card loads a card once per dispatch run. A card that no longer exists is
+// skipped and the message goes out without it.
+func (w *Worker) card(ctx context.Context, cards map[uint64]*models.Card, id uint64) (*models.Card, error) {
+	if card, ok := cards[id]; ok {
+		return card, nil
+	}
+
+	card, _, err := w.templates.GetCard(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cards[id] = card
+	return card, nil
+}
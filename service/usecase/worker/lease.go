@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+	utils "vk-rest/pkg"
+	errs "vk-rest/pkg/errors"
+	"vk-rest/pkg/models"
+	"vk-rest/service/repository/outbox"
+)
+
+const (
+	leaseName  = "birthday-worker"
+	leaseTTL   = 30 * time.Second
+	leaseRenew = 10 * time.Second
+)
+
+// withLease runs job only if this replica takes the worker lease, and
+// returns errs.ErrWorkerBusy when another run holds it. The lease is renewed
+// while job runs, and job's context is cancelled when it is lost. Messages
+// job queues are fenced, so a replica that lost the lease without noticing
+// cannot queue anything after a new leader has started.
+func (w *Worker) withLease(job func(ctx context.Context, run *Worker)) error {
+	owner, err := utils.RandToken(16)
+	if err != nil {
+		return fmt.Errorf("lease owner error: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	acquired, err := w.leases.Acquire(ctx, leaseName, owner, leaseTTL)
+	if err != nil {
+		return fmt.Errorf("acquire lease error: %s", err.Error())
+	}
+
+	if !acquired {
+		return errs.ErrWorkerBusy
+	}
+
+	defer func() {
+		err := w.leases.Release(context.Background(), leaseName, owner)
+		if err != nil {
+			w.log.Errorf("Error in Release: %v", err)
+		}
+	}()
+
+	fence, err := w.state.NextFence(ctx)
+	if err != nil {
+		return err
+	}
+
+	go w.renewLease(ctx, cancel, owner)
+
+	job(ctx, w.withOutbox(&fencedOutbox{IOutboxRepo: w.outbox, fence: fence}))
+	return nil
+}
+
+func (w *Worker) renewLease(ctx context.Context, cancel context.CancelFunc, owner string) {
+	ticker := time.NewTicker(leaseRenew)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := w.leases.Renew(ctx, leaseName, owner, leaseTTL)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				w.log.Errorf("Error in Renew: %v", err)
+			}
+
+			if err != nil || !renewed {
+				w.log.Error("Worker lease lost, stopping the run")
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// withOutbox returns a worker for a single run that queues messages into
+// repo.
+func (w *Worker) withOutbox(repo outbox.IOutboxRepo) *Worker {
+	return &Worker{
+		log:       w.log,
+		now:       w.now,
+		leapDay:   w.leapDay,
+		catchUp:   w.catchUp,
+		notifiers: w.notifiers,
+		profiles:  w.profiles,
+		outbox:    repo,
+		templates: w.templates,
+		state:     w.state,
+		leases:    w.leases,
+	}
+}
+
+// fencedOutbox marks every message with the fencing token of the run.
+type fencedOutbox struct {
+	outbox.IOutboxRepo
+	fence int64
+}
+
+func (f *fencedOutbox) Enqueue(ctx context.Context, msg *models.OutboxMessage) (bool, error) {
+	msg.Fence = f.fence
+	return f.IOutboxRepo.Enqueue(ctx, msg)
+}
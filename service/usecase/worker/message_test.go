@@ -0,0 +1,172 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"gopkg.in/gomail.v2"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"vk-rest/pkg/models"
+	"vk-rest/pkg/render"
+)
+
+type mimePart struct {
+	mediaType string
+	header    map[string][]string
+	body      []byte
+	parts     []*mimePart
+}
+
+// parseMIME reads the message written by NewMessage into a tree of parts.
+func parseMIME(t *testing.T, m *gomail.Message) *mimePart {
+	t.Helper()
+
+	var raw bytes.Buffer
+	_, err := m.WriteTo(&raw)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(&raw)
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+
+	return parsePart(t, msg.Header, msg.Body)
+}
+
+func parsePart(t *testing.T, header map[string][]string, body io.Reader) *mimePart {
+	t.Helper()
+
+	get := func(key string) string {
+		if values := header[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	mediaType, params, err := mime.ParseMediaType(get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType %q: %v", get("Content-Type"), err)
+	}
+
+	part := &mimePart{mediaType: mediaType, header: header}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			next, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("NextPart: %v", err)
+			}
+
+			part.parts = append(part.parts, parsePart(t, next.Header, next))
+		}
+
+		return part
+	}
+
+	switch get("Content-Transfer-Encoding") {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	part.body, err = io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read part: %v", err)
+	}
+
+	return part
+}
+
+func TestNewMessagePlain(t *testing.T) {
+	root := parseMIME(t, NewMessage("bot@example.com", &models.Mail{To: "ivan@example.com", Subject: "Hi", Body: "Happy birthday"}))
+
+	if root.mediaType != "text/plain" || string(root.body) != "Happy birthday" {
+		t.Errorf("got %s %q, want text/plain body", root.mediaType, root.body)
+	}
+}
+
+func TestNewMessageAlternative(t *testing.T) {
+	root := parseMIME(t, NewMessage("bot@example.com", &models.Mail{
+		To:      "ivan@example.com",
+		Subject: "Hi",
+		Body:    "Happy birthday",
+		HTML:    "<p>Happy birthday</p>",
+	}))
+
+	if root.mediaType != "multipart/alternative" || len(root.parts) != 2 {
+		t.Fatalf("got %s with %d parts, want multipart/alternative with 2", root.mediaType, len(root.parts))
+	}
+
+	if root.parts[0].mediaType != "text/plain" || string(root.parts[0].body) != "Happy birthday" {
+		t.Errorf("first part = %s %q", root.parts[0].mediaType, root.parts[0].body)
+	}
+
+	if root.parts[1].mediaType != "text/html" || string(root.parts[1].body) != "<p>Happy birthday</p>" {
+		t.Errorf("second part = %s %q", root.parts[1].mediaType, root.parts[1].body)
+	}
+}
+
+func TestNewMessageWithCard(t *testing.T) {
+	card := &models.Card{ContentType: "image/png", Data: []byte("\x89PNG\r\n\x1a\nfake image")}
+
+	rendered, err := render.Render(&models.Template{Subject: "Hi", Body: "Happy birthday, {{.Name}}!", CardId: 1}, &models.TemplateData{Name: "ivan"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	root := parseMIME(t, NewMessage("bot@example.com", &models.Mail{
+		To:      "ivan@example.com",
+		Subject: rendered.Subject,
+		Body:    rendered.Text,
+		HTML:    rendered.HTML,
+		Card:    card,
+	}))
+
+	if root.mediaType != "multipart/related" || len(root.parts) != 2 {
+		t.Fatalf("got %s with %d parts, want multipart/related with 2", root.mediaType, len(root.parts))
+	}
+
+	alternative := root.parts[0]
+	if alternative.mediaType != "multipart/alternative" || len(alternative.parts) != 2 {
+		t.Fatalf("first related part = %s with %d parts, want multipart/alternative with 2", alternative.mediaType, len(alternative.parts))
+	}
+
+	plain, html := alternative.parts[0], alternative.parts[1]
+	if plain.mediaType != "text/plain" || string(plain.body) != "Happy birthday, ivan!" {
+		t.Errorf("plain part = %s %q", plain.mediaType, plain.body)
+	}
+
+	if html.mediaType != "text/html" || !strings.Contains(string(html.body), "Happy birthday, ivan!") {
+		t.Errorf("html part = %s %q", html.mediaType, html.body)
+	}
+
+	reference := `src="cid:` + render.CardContentId + `"`
+	if !strings.Contains(string(html.body), reference) {
+		t.Errorf("html part does not refer to %s: %q", reference, html.body)
+	}
+
+	image := root.parts[1]
+	if image.mediaType != card.ContentType {
+		t.Errorf("image part type = %s, want %s", image.mediaType, card.ContentType)
+	}
+
+	if got := image.header["Content-Id"]; len(got) != 1 || got[0] != "<"+render.CardContentId+">" {
+		t.Errorf("image Content-ID = %v, want <%s>", got, render.CardContentId)
+	}
+
+	if !bytes.Equal(image.body, card.Data) {
+		t.Errorf("image data = %q, want %q", image.body, card.Data)
+	}
+}
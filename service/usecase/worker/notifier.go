@@ -0,0 +1,241 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/gomail.v2"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"vk-rest/pkg/models"
+	"vk-rest/pkg/render"
+)
+
+const notifierTimeout = 10 * time.Second
+
+// Notifier delivers a rendered mail over one channel. mail.To holds the
+// channel specific address of the recipient.
+type Notifier interface {
+	Notify(ctx context.Context, mail *models.Mail) error
+}
+
+// GetNotifiers builds a notifier for every channel that can be served with
+// the given configuration. Telegram is only available when a bot token is set.
+// Webhook and Slack URLs are chosen by employees, so they are called through
+// a client that only reaches public addresses.
+func GetNotifiers(mailCfg *models.MailConfigServer, cfg *models.NotifierConfig) map[string]Notifier {
+	public := publicClient()
+
+	notifiers := map[string]Notifier{
+		models.ChannelEmail:   &SMTPNotifier{config: mailCfg},
+		models.ChannelWebhook: &WebhookNotifier{client: public},
+		models.ChannelSlack:   &SlackNotifier{client: public},
+	}
+
+	if cfg.TelegramToken != "" {
+		notifiers[models.ChannelTelegram] = &TelegramNotifier{
+			client: &http.Client{Timeout: notifierTimeout},
+			apiUrl: cfg.TelegramApiUrl,
+			token:  cfg.TelegramToken,
+		}
+	}
+
+	return notifiers
+}
+
+type SMTPNotifier struct {
+	config *models.MailConfigServer
+}
+
+func (n *SMTPNotifier) Notify(_ context.Context, mail *models.Mail) error {
+	m := NewMessage(n.config.AddrEmail, mail)
+
+	d := gomail.NewDialer(n.config.AddrHost, n.config.Port, n.config.AddrEmail, n.config.Password)
+
+	err := d.DialAndSend(m)
+	return err
+}
+
+// NewMessage builds the e-mail for mail. With an HTML part it is a
+// multipart/alternative message, and with a card the alternative is wrapped
+// into multipart/related together with the inline image.
+func NewMessage(from string, mail *models.Mail) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", from)
+	m.SetHeader("To", mail.To)
+	m.SetHeader("Subject", mail.Subject)
+	m.SetBody("text/plain", mail.Body)
+
+	if mail.HTML == "" {
+		return m
+	}
+
+	m.AddAlternative("text/html", mail.HTML)
+
+	if mail.Card != nil {
+		data := mail.Card.Data
+		m.Embed(cardFileName(mail.Card.ContentType),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			}),
+			gomail.SetHeader(map[string][]string{
+				"Content-ID":   {"<" + render.CardContentId + ">"},
+				"Content-Type": {mail.Card.ContentType},
+			}),
+		)
+	}
+
+	return m
+}
+
+func cardFileName(contentType string) string {
+	switch contentType {
+	case "image/jpeg":
+		return render.CardContentId + ".jpg"
+	case "image/gif":
+		return render.CardContentId + ".gif"
+	}
+
+	return render.CardContentId + ".png"
+}
+
+// WebhookNotifier posts the mail as JSON to the URL chosen by the recipient.
+type WebhookNotifier struct {
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, mail *models.Mail) error {
+	if !strings.HasPrefix(mail.To, "https://") {
+		return errNotHTTPS
+	}
+
+	return postJSON(ctx, n.client, mail.To, webhookPayload{Subject: mail.Subject, Body: mail.Body})
+}
+
+// SlackNotifier posts to a Slack compatible incoming webhook URL.
+type SlackNotifier struct {
+	client *http.Client
+}
+
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, mail *models.Mail) error {
+	if !strings.HasPrefix(mail.To, "https://") {
+		return errNotHTTPS
+	}
+
+	return postJSON(ctx, n.client, mail.To, slackPayload{Text: "*" + mail.Subject + "*\n" + mail.Body})
+}
+
+// TelegramNotifier sends a message through the Telegram Bot API to the chat
+// id chosen by the recipient.
+type TelegramNotifier struct {
+	client *http.Client
+	apiUrl string
+	token  string
+}
+
+type telegramPayload struct {
+	ChatId string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func (n *TelegramNotifier) Notify(ctx context.Context, mail *models.Mail) error {
+	target := strings.TrimRight(n.apiUrl, "/") + "/bot" + n.token + "/sendMessage"
+	return postJSON(ctx, n.client, target, telegramPayload{ChatId: mail.To, Text: mail.Subject + "\n\n" + mail.Body})
+}
+
+func postJSON(ctx context.Context, client *http.Client, target string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload error: %s", err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request error: %s", err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// the url may carry a bot token, keep it out of logs and the outbox
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("post request error: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+var errNotHTTPS = errors.New("only https urls are allowed")
+
+// publicClient returns a client that refuses to connect to loopback,
+// private, link-local and other non-public addresses. The address is checked
+// after name resolution, so a public name pointing inside the network and
+// redirects are refused too.
+func publicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: notifierTimeout,
+		Control: checkPublicAddr,
+	}
+
+	return &http.Client{
+		Timeout: notifierTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: notifierTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return errNotHTTPS
+			}
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, not covered by
+// netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func checkPublicAddr(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("bad address %s: %s", address, err.Error())
+	}
+
+	addr := addrPort.Addr().Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) {
+		return fmt.Errorf("address %s is not public", addr)
+	}
+
+	return nil
+}
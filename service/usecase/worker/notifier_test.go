@@ -0,0 +1,212 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vk-rest/pkg/models"
+)
+
+type capturedRequest struct {
+	method      string
+	path        string
+	contentType string
+	body        []byte
+}
+
+func captureServer(t *testing.T, tls bool, status int) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+
+	captured := &capturedRequest{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+
+		captured.method = r.Method
+		captured.path = r.URL.Path
+		captured.contentType = r.Header.Get("Content-Type")
+		captured.body = body
+		w.WriteHeader(status)
+	})
+
+	var srv *httptest.Server
+	if tls {
+		srv = httptest.NewTLSServer(handler)
+	} else {
+		srv = httptest.NewServer(handler)
+	}
+	t.Cleanup(srv.Close)
+
+	return srv, captured
+}
+
+var testMail = &models.Mail{Subject: "Birthday", Body: "Today is ivan's birthday"}
+
+func TestNotifierPayloads(t *testing.T) {
+	tests := []struct {
+		name     string
+		notifier func(srv *httptest.Server) Notifier
+		to       func(srv *httptest.Server) string
+		tls      bool
+		path     string
+		want     map[string]string
+	}{
+		{
+			name:     "webhook",
+			notifier: func(srv *httptest.Server) Notifier { return &WebhookNotifier{client: srv.Client()} },
+			to:       func(srv *httptest.Server) string { return srv.URL + "/hook" },
+			tls:      true,
+			path:     "/hook",
+			want:     map[string]string{"subject": "Birthday", "body": "Today is ivan's birthday"},
+		},
+		{
+			name:     "slack",
+			notifier: func(srv *httptest.Server) Notifier { return &SlackNotifier{client: srv.Client()} },
+			to:       func(srv *httptest.Server) string { return srv.URL + "/services/T0/B0/X" },
+			tls:      true,
+			path:     "/services/T0/B0/X",
+			want:     map[string]string{"text": "*Birthday*\nToday is ivan's birthday"},
+		},
+		{
+			name: "telegram",
+			notifier: func(srv *httptest.Server) Notifier {
+				return &TelegramNotifier{client: srv.Client(), apiUrl: srv.URL + "/", token: "123:abc"}
+			},
+			to:   func(*httptest.Server) string { return "-1001" },
+			path: "/bot123:abc/sendMessage",
+			want: map[string]string{"chat_id": "-1001", "text": "Birthday\n\nToday is ivan's birthday"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, captured := captureServer(t, tt.tls, http.StatusOK)
+
+			mail := *testMail
+			mail.To = tt.to(srv)
+
+			err := tt.notifier(srv).Notify(context.Background(), &mail)
+			if err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+
+			if captured.method != http.MethodPost || captured.path != tt.path {
+				t.Errorf("request = %s %s, want POST %s", captured.method, captured.path, tt.path)
+			}
+
+			if captured.contentType != "application/json" {
+				t.Errorf("content type = %q", captured.contentType)
+			}
+
+			var got map[string]string
+			err = json.Unmarshal(captured.body, &got)
+			if err != nil {
+				t.Fatalf("unmarshal payload %s: %v", captured.body, err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Errorf("payload = %v, want %v", got, tt.want)
+			}
+			for key, value := range tt.want {
+				if got[key] != value {
+					t.Errorf("payload[%s] = %q, want %q", key, got[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestNotifierErrorStatus(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError} {
+		srv, _ := captureServer(t, true, status)
+
+		notifiers := map[string]Notifier{
+			"webhook":  &WebhookNotifier{client: srv.Client()},
+			"slack":    &SlackNotifier{client: srv.Client()},
+			"telegram": &TelegramNotifier{client: srv.Client(), apiUrl: srv.URL, token: "123:secret"},
+		}
+
+		for name, notifier := range notifiers {
+			mail := *testMail
+			mail.To = srv.URL
+
+			err := notifier.Notify(context.Background(), &mail)
+			if err == nil {
+				t.Errorf("%s with status %d: want error", name, status)
+				continue
+			}
+
+			if strings.Contains(err.Error(), "secret") {
+				t.Errorf("%s error leaks the token: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestWebhookRequiresHTTPS(t *testing.T) {
+	srv, captured := captureServer(t, false, http.StatusOK)
+
+	for _, notifier := range []Notifier{&WebhookNotifier{client: srv.Client()}, &SlackNotifier{client: srv.Client()}} {
+		mail := *testMail
+		mail.To = srv.URL
+
+		err := notifier.Notify(context.Background(), &mail)
+		if err == nil {
+			t.Errorf("%T accepted %s", notifier, srv.URL)
+		}
+	}
+
+	if captured.method != "" {
+		t.Errorf("plain http server was called")
+	}
+}
+
+func TestPublicClientRefusesLocalTargets(t *testing.T) {
+	srv, captured := captureServer(t, true, http.StatusOK)
+
+	mail := *testMail
+	mail.To = srv.URL
+
+	err := (&WebhookNotifier{client: publicClient()}).Notify(context.Background(), &mail)
+	if err == nil || !strings.Contains(err.Error(), "not public") {
+		t.Errorf("Notify to %s: err = %v, want not public", srv.URL, err)
+	}
+
+	if captured.method != "" {
+		t.Errorf("loopback server was called")
+	}
+}
+
+func TestCheckPublicAddr(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"8.8.8.8:443", true},
+		{"[2606:4700:4700::1111]:443", true},
+		{"127.0.0.1:443", false},
+		{"[::1]:443", false},
+		{"10.1.2.3:443", false},
+		{"172.16.0.1:443", false},
+		{"192.168.1.1:443", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:443", false},
+		{"0.0.0.0:443", false},
+		{"[fe80::1]:443", false},
+		{"[fd00::1]:443", false},
+		{"[::ffff:127.0.0.1]:443", false},
+	}
+
+	for _, tt := range tests {
+		err := checkPublicAddr("tcp", tt.address, nil)
+		if (err == nil) != tt.public {
+			t.Errorf("checkPublicAddr(%s) = %v, want public %t", tt.address, err, tt.public)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"vk-rest/pkg/birthday"
+	errs "vk-rest/pkg/errors"
+	"vk-rest/pkg/models"
+	"vk-rest/service/repository/outbox"
+)
+
+// RunTriggers does the manual runs recorded through the API on the replica
+// that gets the worker lease, so they are fenced like the scheduled runs.
+// While another run holds the lease the triggers wait for the next tick.
+func (w *Worker) RunTriggers() {
+	open, err := w.state.HasOpenTriggers(context.Background())
+	if err != nil {
+		w.log.Errorf("Error in HasOpenTriggers: %v", err)
+		return
+	}
+
+	if !open {
+		return
+	}
+
+	err = w.withLease(func(ctx context.Context, run *Worker) {
+		run.runTriggers(ctx)
+	})
+	if err != nil && !errors.Is(err, errs.ErrWorkerBusy) {
+		w.log.Errorf("Error in RunTriggers: %v", err)
+	}
+}
+
+// runTriggers does the pending triggers one by one. A trigger whose run has
+// lost the lease stays running and is claimed again by the next leader.
+func (w *Worker) runTriggers(ctx context.Context) {
+	for ctx.Err() == nil {
+		trigger, found, err := w.state.ClaimTrigger(ctx, w.now().Add(-leaseTTL))
+		if err != nil {
+			w.log.Errorf("Error in ClaimTrigger: %v", err)
+			return
+		}
+
+		if !found {
+			return
+		}
+
+		var messages []*models.PlannedMessage
+		date, err := time.Parse(time.DateOnly, trigger.Date)
+		if err == nil {
+			messages, err = w.trigger(ctx, date, trigger.DryRun)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		trigger.Status, trigger.Messages = models.TriggerDone, messages
+		if err != nil {
+			trigger.Status, trigger.Messages, trigger.Error = models.TriggerFailed, make([]*models.PlannedMessage, 0), err.Error()
+		}
+
+		err = w.state.FinishTrigger(ctx, trigger)
+		if err != nil {
+			w.log.Errorf("Error in FinishTrigger %d: %v", trigger.Id, err)
+		}
+	}
+}
+
+// trigger runs the birthday job for date as it runs at the greeting hour in
+// every time zone, whether the worker is paused or not. In the time zones
+// where date has already passed only the belated greetings and notify
+// messages are sent, as catchUpDays does. In a dry run nothing is put into
+// the outbox. It returns the messages the run produced.
+func (w *Worker) trigger(ctx context.Context, date time.Time, dryRun bool) ([]*models.PlannedMessage, error) {
+	timezones, err := w.profiles.GetTimezones(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("trigger error: %s", err.Error())
+	}
+
+	rec := &recorder{IOutboxRepo: w.outbox, dryRun: dryRun, messages: make([]*models.PlannedMessage, 0)}
+	run := w.withOutbox(rec)
+	now := w.now()
+
+	for _, timezone := range timezones {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			w.log.Errorf("Error in LoadLocation %s: %v", timezone, err)
+			continue
+		}
+
+		local := time.Date(date.Year(), date.Month(), date.Day(), greetingHour, 0, 0, 0, loc)
+		if birthday.Date(local).Before(birthday.Date(now.In(loc))) {
+			run.catchUpDay(ctx, timezone, local)
+			continue
+		}
+
+		run.runTimezone(ctx, timezone, local)
+	}
+
+	return rec.messages, nil
+}
+
+// recorder remembers every message put into the outbox and, in a dry run,
+// does not store them.
+type recorder struct {
+	outbox.IOutboxRepo
+	dryRun   bool
+	messages []*models.PlannedMessage
+}
+
+func (r *recorder) Enqueue(ctx context.Context, msg *models.OutboxMessage) (bool, error) {
+	var added bool
+	if !r.dryRun {
+		var err error
+		added, err = r.IOutboxRepo.Enqueue(ctx, msg)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	r.messages = append(r.messages, &models.PlannedMessage{
+		RecipientId: msg.RecipientId,
+		BirthdayId:  msg.BirthdayId,
+		Kind:        msg.Kind,
+		Channel:     msg.Channel,
+		To:          msg.To,
+		Subject:     msg.Subject,
+		Body:        msg.Body,
+		Queued:      added,
+	})
+
+	return added, nil
+}
@@ -2,16 +2,32 @@ package worker
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/robfig/cron"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/gomail.v2"
 	"os"
+	"sort"
 	"strconv"
+	"sync/atomic"
+	"time"
 	"vk-rest/configs"
 	utils "vk-rest/pkg"
+	"vk-rest/pkg/birthday"
+	errs "vk-rest/pkg/errors"
 	"vk-rest/pkg/models"
+	"vk-rest/pkg/render"
+	"vk-rest/service/repository/lease"
+	"vk-rest/service/repository/outbox"
 	"vk-rest/service/repository/profile"
+	"vk-rest/service/repository/state"
+	"vk-rest/service/repository/template"
+)
+
+const (
+	greetingHour = 8
+	statePoll    = 30 * time.Second
 )
 
 type IWorker interface {
@@ -19,24 +35,67 @@ type IWorker interface {
 }
 
 type Worker struct {
-	log      *logrus.Logger
-	config   *models.MailConfigServer
-	profiles profile.IProfileRepo
+	log       *logrus.Logger
+	now       func() time.Time
+	leapDay   birthday.LeapDayPolicy
+	catchUp   int
+	notifiers map[string]Notifier
+	profiles  profile.IProfileRepo
+	outbox    outbox.IOutboxRepo
+	templates template.ITemplateRepo
+	state     state.IStateRepo
+	leases    lease.ILeaseRepo
+
+	cron     *cron.Cron
+	schedule string
+	paused   atomic.Bool
 }
 
-func GetWorker(psxCfg *configs.DbPsxConfig, log *logrus.Logger) (IWorker, error) {
-	profileRepo, err := profile.GetPsxRepo(psxCfg, log)
+func GetWorker(db *sql.DB, redisCfg *configs.DbRedisCfg, birthdayCfg *configs.BirthdayCfg, log *logrus.Logger) (IWorker, error) {
+	leaseRepo, err := lease.GetLeaseRepo(redisCfg, log)
 	if err != nil {
-		log.Error("Get GetFilmRepo error: ", err)
+		log.Error("Get GetLeaseRepo error: ", err)
 		return nil, err
 	}
 
-	port, err := strconv.Atoi(os.Getenv("PORT_HOST_MAIL"))
+	cfg, err := GetMailConfig()
 	if err != nil {
-		log.Errorf("Error in GetPort: %v", err)
+		log.Errorf("Error in GetMailConfig: %v", err)
 		return nil, err
 	}
 
+	notifierCfg := &models.NotifierConfig{
+		TelegramToken:  os.Getenv("TELEGRAM_BOT_TOKEN"),
+		TelegramApiUrl: os.Getenv("TELEGRAM_API_URL"),
+	}
+
+	if notifierCfg.TelegramApiUrl == "" {
+		notifierCfg.TelegramApiUrl = "https://api.telegram.org"
+	}
+
+	worker := &Worker{
+		log:       log,
+		now:       time.Now,
+		leapDay:   birthdayCfg.LeapDayPolicy,
+		catchUp:   birthdayCfg.CatchUpDays,
+		notifiers: GetNotifiers(cfg, notifierCfg),
+		profiles:  profile.GetPsxRepo(db),
+		outbox:    outbox.GetOutboxRepo(db),
+		templates: template.GetTemplateRepo(db),
+		state:     state.GetStateRepo(db),
+		leases:    leaseRepo,
+	}
+
+	return worker, nil
+}
+
+// GetMailConfig reads the SMTP server settings from the environment.
+func GetMailConfig() (*models.MailConfigServer, error) {
+	port, err := strconv.Atoi(os.Getenv("PORT_HOST_MAIL"))
+	if err != nil {
+		return nil, fmt.Errorf("bad mail port: %s", err.Error())
+	}
+
 	cfg := &models.MailConfigServer{
 		AddrEmail: os.Getenv("EMAIL_ADDRESS_SERVER"),
 		Password:  os.Getenv("EMAIL_PASSWORD_SERVER"),
@@ -44,91 +103,475 @@ func GetWorker(psxCfg *configs.DbPsxConfig, log *logrus.Logger) (IWorker, error)
 		AddrHost:  os.Getenv("ADDRESS_HOST_MAIL"),
 	}
 
-	worker := &Worker{
-		log:      log,
-		config:   cfg,
-		profiles: profileRepo,
+	return cfg, nil
+}
+
+func (w *Worker) StartWorker() error {
+	workerState, err := w.state.GetWorkerState(context.Background())
+	if err != nil {
+		return fmt.Errorf("worker state error: %s", err.Error())
 	}
 
-	return worker, nil
+	w.paused.Store(workerState.Paused)
+	err = w.startCron(workerState.Schedule)
+	if err != nil {
+		return err
+	}
+
+	go w.watchState()
+
+	w.log.Infof("Worker started with schedule %s, paused: %t", workerState.Schedule, workerState.Paused)
+	return nil
 }
 
-func (w *Worker) StartWorker() error {
-	w.log.Info("Worker started")
+// startCron replaces the running cron with one that runs the birthday job
+// on schedule.
+func (w *Worker) startCron(schedule string) error {
 	c := cron.New()
 
-	//запуск раз в день в 08.00
-	err := c.AddFunc("0 0 8 * * *", w.HappyBirthday)
+	//поздравление ставится в очередь с 08.00 по времени сотрудника
+	err := c.AddFunc(schedule, w.HappyBirthday)
+	if err != nil {
+		return fmt.Errorf("cron error: %s", err.Error())
+	}
+
+	//отправка писем из очереди
+	err = c.AddFunc("*/30 * * * * *", w.Dispatch)
+	if err != nil {
+		return fmt.Errorf("cron error: %s", err.Error())
+	}
+
+	//ручные запуски, записанные через API
+	err = c.AddFunc("*/10 * * * * *", w.RunTriggers)
 	if err != nil {
 		return fmt.Errorf("cron error: %s", err.Error())
 	}
 
 	c.Start()
+	if w.cron != nil {
+		w.cron.Stop()
+	}
+
+	w.cron = c
+	w.schedule = schedule
 	return nil
 }
 
+// watchState applies the schedule and the pause flag set through the API.
+func (w *Worker) watchState() {
+	ticker := time.NewTicker(statePoll)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		workerState, err := w.state.GetWorkerState(context.Background())
+		if err != nil {
+			w.log.Errorf("Error in GetWorkerState: %v", err)
+			continue
+		}
+
+		if w.paused.Swap(workerState.Paused) != workerState.Paused {
+			w.log.Infof("Worker paused: %t", workerState.Paused)
+		}
+
+		if workerState.Schedule == w.schedule {
+			continue
+		}
+
+		err = w.startCron(workerState.Schedule)
+		if err != nil {
+			w.log.Errorf("Error in startCron %s: %v", workerState.Schedule, err)
+			continue
+		}
+
+		w.log.Infof("Worker schedule changed to %s", workerState.Schedule)
+	}
+}
+
+// HappyBirthday runs the birthday job on the replica that gets the worker
+// lease; the other replicas skip the tick.
 func (w *Worker) HappyBirthday() {
-	ctx := context.Background()
+	paused := w.paused.Load()
+
+	err := w.withLease(func(ctx context.Context, run *Worker) {
+		run.happyBirthday(ctx, paused)
+	})
+	if err != nil && !errors.Is(err, errs.ErrWorkerBusy) {
+		w.log.Errorf("Error in HappyBirthday: %v", err)
+	}
+}
 
-	employees, err := w.GetEmployeesBirthToday(ctx)
+// happyBirthday catches up missed days and puts greetings for every time
+// zone where the greeting hour has already come into the outbox. The outbox
+// ignores messages it already has, so every tick of the day may repeat the
+// work safely. The day is recorded as done even while the worker is paused,
+// so days skipped on purpose are not caught up later.
+func (w *Worker) happyBirthday(ctx context.Context, paused bool) {
+	if !paused {
+		w.catchUpDays(ctx)
+	}
+
+	now := w.now()
+
+	timezones, err := w.profiles.GetTimezones(ctx)
 	if err != nil {
-		w.log.Errorf("Error in CheckBirthday: %v", err)
+		w.log.Errorf("Error in GetTimezones: %v", err)
 		return
 	}
 
-	for _, employee := range employees {
-		mail := &models.Mail{
-			To:      employee.Email,
-			Subject: utils.HeaderBirthdayEmp,
-			Body:    utils.BodyBirthdayToEmp,
+	for _, timezone := range timezones {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			w.log.Errorf("Error in LoadLocation %s: %v", timezone, err)
+			continue
 		}
 
-		err = w.SendMail(mail, w.config)
+		local := now.In(loc)
+		if local.Hour() < greetingHour {
+			continue
+		}
+
+		if !paused {
+			w.runTimezone(ctx, timezone, local)
+		}
+
+		err = w.state.SetLastRun(ctx, timezone, local)
 		if err != nil {
-			w.log.Info(err)
-			return
+			w.log.Errorf("Error in SetLastRun %s: %v", timezone, err)
+		}
+	}
+}
+
+// runTimezone does the work of the local day in timezone: greetings,
+// reminders and digests.
+func (w *Worker) runTimezone(ctx context.Context, timezone string, local time.Time) {
+	w.greetTimezone(ctx, timezone, local, false)
+
+	days, err := w.profiles.GetReminderDays(ctx)
+	if err != nil {
+		w.log.Errorf("Error in GetReminderDays: %v", err)
+	} else {
+		for _, day := range days {
+			w.remindTimezone(ctx, timezone, local, day, false)
 		}
+	}
 
-		employeesByBirthday, err := w.profiles.GetEmployeeByBirthday(ctx, employee.Id)
+	w.digestTimezone(ctx, timezone, local)
+}
+
+// catchUpDays replays the days missed while no worker was running, up to
+// the configured number of days back. Only greetings and the notify messages
+// of the day are replayed, marked as belated; reminders and digests of the
+// past days are out of date. Time zones the worker has never run for are
+// skipped.
+func (w *Worker) catchUpDays(ctx context.Context) {
+	if w.catchUp == 0 {
+		return
+	}
+
+	now := w.now()
+
+	runs, err := w.state.GetLastRuns(ctx)
+	if err != nil {
+		w.log.Errorf("Error in GetLastRuns: %v", err)
+		return
+	}
+
+	timezones, err := w.profiles.GetTimezones(ctx)
+	if err != nil {
+		w.log.Errorf("Error in GetTimezones: %v", err)
+		return
+	}
+
+	for _, timezone := range timezones {
+		lastRun, ok := runs[timezone]
+		if !ok {
+			continue
+		}
+
+		loc, err := time.LoadLocation(timezone)
 		if err != nil {
-			w.log.Info(err)
-			return
+			w.log.Errorf("Error in LoadLocation %s: %v", timezone, err)
+			continue
 		}
 
-		mail.Subject = utils.HeaderBirthdayEmp
-		mail.Body = fmt.Sprintf(utils.BodyBirthdayFromEmp, employee.Login)
+		today := birthday.Date(now.In(loc))
+		from := birthday.Date(lastRun).AddDate(0, 0, 1)
+		if earliest := today.AddDate(0, 0, -w.catchUp); from.Before(earliest) {
+			from = earliest
+		}
 
-		for _, employeeByBirthday := range employeesByBirthday {
-			mail.To = employeeByBirthday.Email
+		for day := from; day.Before(today); day = day.AddDate(0, 0, 1) {
+			local := time.Date(day.Year(), day.Month(), day.Day(), greetingHour, 0, 0, 0, loc)
+			w.log.Infof("Catching up %s in %s", day.Format(time.DateOnly), timezone)
+
+			w.catchUpDay(ctx, timezone, local)
 
-			err = w.SendMail(mail, w.config)
+			err = w.state.SetLastRun(ctx, timezone, local)
 			if err != nil {
-				w.log.Info(err)
-				return
+				w.log.Errorf("Error in SetLastRun %s: %v", timezone, err)
 			}
 		}
+	}
+}
+
+// catchUpDay does the work of a local day in timezone that has passed: only
+// the greetings and the notify messages, marked as belated.
+func (w *Worker) catchUpDay(ctx context.Context, timezone string, local time.Time) {
+	w.greetTimezone(ctx, timezone, local, true)
+	w.remindTimezone(ctx, timezone, local, 0, true)
+}
+
+// greetTimezone greets the employees whose birthday is on the local date.
+// Belated greetings are sent by catchUpDays after the day has passed.
+func (w *Worker) greetTimezone(ctx context.Context, timezone string, local time.Time, belated bool) {
+	employees, err := w.GetEmployeesBirthToday(ctx, timezone, local)
+	if err != nil {
+		w.log.Errorf("Error in CheckBirthday: %v", err)
+		return
+	}
+
+	for _, employee := range employees {
+		data := &models.TemplateData{
+			Name:       employee.Login,
+			Age:        age(employee, local),
+			Department: employee.Department,
+			Date:       local.Format(time.DateOnly),
+			Belated:    belated,
+		}
+
+		w.enqueue(ctx, employee, models.OutboxKindGreeting, data, &models.OutboxMessage{
+			RecipientId: employee.Id,
+			BirthdayId:  employee.Id,
+			Year:        local.Year(),
+			Kind:        models.OutboxKindGreeting,
+		})
+	}
+}
+
+// remindTimezone reminds the subscribers of the employees whose birthday is
+// days after the local date. The reminder on the day itself is the notify
+// message, earlier ones use the reminder template and have a kind per
+// offset, so every reminder is sent once a year.
+func (w *Worker) remindTimezone(ctx context.Context, timezone string, local time.Time, days int, belated bool) {
+	day := local.AddDate(0, 0, days)
+
+	employees, err := w.GetEmployeesBirthToday(ctx, timezone, day)
+	if err != nil {
+		w.log.Errorf("Error in CheckBirthday: %v", err)
+		return
+	}
+
+	name, kind := models.OutboxKindNotify, models.OutboxKindNotify
+	if days > 0 {
+		name, kind = models.OutboxKindReminder, models.ReminderKind(days)
+	}
+
+	for _, employee := range employees {
+		data := &models.TemplateData{
+			Name:       employee.Login,
+			Age:        age(employee, day),
+			Department: employee.Department,
+			DaysUntil:  days,
+			Date:       day.Format(time.DateOnly),
+			Belated:    belated,
+		}
+
+		if employee.Visibility == models.VisibilityNoYear {
+			data.Age = 0
+		}
+
+		employeesByBirthday, err := w.profiles.GetEmployeeByBirthday(ctx, employee.Id, days)
+		if err != nil {
+			w.log.Errorf("Error in GetEmployeeByBirthday %d: %v", employee.Id, err)
+			continue
+		}
 
+		for _, employeeByBirthday := range employeesByBirthday {
+			w.enqueue(ctx, employeeByBirthday, name, data, &models.OutboxMessage{
+				RecipientId: employeeByBirthday.Id,
+				BirthdayId:  employee.Id,
+				Year:        day.Year(),
+				Kind:        kind,
+			})
+		}
 	}
 }
 
-func (w *Worker) GetEmployeesBirthToday(ctx context.Context) ([]*models.UserItem, error) {
-	employees, err := w.profiles.GetBirthdayEmployees(ctx)
+// digestTimezone sends the daily digests, and on Mondays the weekly ones, to
+// the employees of timezone.
+func (w *Worker) digestTimezone(ctx context.Context, timezone string, local time.Time) {
+	modes := []string{models.DeliveryDaily}
+	if local.Weekday() == time.Monday {
+		modes = append(modes, models.DeliveryWeekly)
+	}
+
+	recipients, err := w.profiles.GetDigestRecipients(ctx, timezone, modes)
+	if err != nil {
+		w.log.Errorf("Error in GetDigestRecipients: %v", err)
+		return
+	}
+
+	for _, recipient := range recipients {
+		w.digest(ctx, recipient, local)
+	}
+}
+
+// digest lists the birthdays of everyone the recipient follows that are
+// celebrated today, or in the seven days from today for the weekly digest.
+// Empty digests are not sent.
+func (w *Worker) digest(ctx context.Context, recipient *models.DigestRecipient, local time.Time) {
+	days, period := 1, local.Format(time.DateOnly)
+	if recipient.DeliveryMode == models.DeliveryWeekly {
+		year, week := local.ISOWeek()
+		days, period = 7, fmt.Sprintf("%d-W%02d", year, week)
+	}
+
+	employees, err := w.profiles.GetSubscribedEmployees(ctx, recipient.Id)
+	if err != nil {
+		w.log.Errorf("Error in GetSubscribedEmployees %d: %v", recipient.Id, err)
+		return
+	}
+
+	today := birthday.Date(local)
+	items := make([]*models.DigestItem, 0)
+	for _, employee := range employees {
+		born, err := utils.ParseDate(employee.Birthday)
+		if err != nil {
+			w.log.Errorf("Error in ParseDate %d: %v", employee.Id, err)
+			continue
+		}
+
+		day := birthday.Next(born, today, w.leapDay)
+		daysUntil := birthday.DaysUntil(today, day)
+		if daysUntil >= days {
+			continue
+		}
+
+		item := &models.DigestItem{
+			Name:       employee.Login,
+			Date:       day.Format(time.DateOnly),
+			Age:        birthday.Age(born, day),
+			Department: employee.Department,
+			DaysUntil:  daysUntil,
+		}
+
+		if employee.Visibility == models.VisibilityNoYear {
+			item.Age = 0
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DaysUntil < items[j].DaysUntil
+	})
+
+	data := &models.TemplateData{
+		Name:      recipient.Login,
+		Birthdays: items,
+	}
+
+	w.enqueue(ctx, &recipient.UserItem, models.OutboxKindDigest, data, &models.OutboxMessage{
+		RecipientId: recipient.Id,
+		BirthdayId:  recipient.Id,
+		Year:        local.Year(),
+		Kind:        models.DigestKind(recipient.DeliveryMode, period),
+	})
+}
+
+// enqueue renders the template called name in the recipient's locale and
+// stores a copy of msg for every channel the recipient has chosen. Employees
+// without chosen channels are reached by e-mail.
+func (w *Worker) enqueue(ctx context.Context, recipient *models.UserItem, name string, data *models.TemplateData, msg *models.OutboxMessage) {
+	rendered, err := w.render(ctx, name, recipient.Locale, data)
+	if err != nil {
+		w.log.Errorf("Error in render %s for %d: %v", msg.Kind, recipient.Id, err)
+		return
+	}
+
+	msg.Subject = rendered.Subject
+	msg.Body = rendered.Text
+	msg.HTML = rendered.HTML
+	msg.CardId = rendered.CardId
+
+	channels, err := w.profiles.GetChannels(ctx, recipient.Id)
+	if err != nil {
+		w.log.Errorf("Error in GetChannels %d: %v", recipient.Id, err)
+		return
+	}
+
+	if len(channels) == 0 {
+		channels = append(channels, &models.Channel{Channel: models.ChannelEmail})
+	}
+
+	for _, channel := range channels {
+		if _, ok := w.notifiers[channel.Channel]; !ok {
+			w.log.Errorf("Channel %s of %d is not configured", channel.Channel, recipient.Id)
+			continue
+		}
+
+		channelMsg := *msg
+		channelMsg.Channel = channel.Channel
+		channelMsg.To = channel.Address
+		if channel.Channel == models.ChannelEmail && channelMsg.To == "" {
+			channelMsg.To = recipient.Email
+		}
+
+		added, err := w.outbox.Enqueue(ctx, &channelMsg)
+		if err != nil {
+			w.log.Errorf("Error in Enqueue %s for %d: %v", msg.Kind, msg.RecipientId, err)
+			continue
+		}
+
+		if added {
+			w.log.Infof("Queued %s for %d about %d via %s", msg.Kind, msg.RecipientId, msg.BirthdayId, channel.Channel)
+		}
+	}
+}
+
+// render executes the template called name for locale, falling back to the
+// default locale when no translation exists.
+func (w *Worker) render(ctx context.Context, name, locale string, data *models.TemplateData) (*models.RenderedMessage, error) {
+	tpl, found, err := w.templates.FindTemplate(ctx, name, locale)
 	if err != nil {
 		return nil, err
 	}
 
-	return employees, nil
+	if !found && locale != utils.DefaultLocale {
+		tpl, found, err = w.templates.FindTemplate(ctx, name, utils.DefaultLocale)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+
+	return render.Render(tpl, data)
 }
 
-func (w *Worker) SendMail(mail *models.Mail, cfg *models.MailConfigServer) error {
-	m := gomail.NewMessage()
-	m.SetHeader("From", cfg.AddrEmail)
-	m.SetHeader("To", mail.To)
-	m.SetHeader("Subject", mail.Subject)
-	m.SetBody("text/plain", mail.Body)
+func age(employee *models.UserItem, local time.Time) int {
+	born, err := utils.ParseDate(employee.Birthday)
+	if err != nil {
+		return 0
+	}
+
+	return birthday.Age(born, local)
+}
 
-	d := gomail.NewDialer(cfg.AddrHost, cfg.Port, cfg.AddrEmail, cfg.Password)
+// GetEmployeesBirthToday returns the employees celebrating on the local
+// date in timezone, including the ones born on February 29 when the leap day policy
+// moves their birthday to it.
+func (w *Worker) GetEmployeesBirthToday(ctx context.Context, timezone string, local time.Time) ([]*models.UserItem, error) {
+	employees, err := w.profiles.GetBirthdayEmployees(ctx, timezone, birthday.MonthDays(local, w.leapDay))
+	if err != nil {
+		return nil, err
+	}
 
-	err := d.DialAndSend(m)
-	return err
+	return employees, nil
 }
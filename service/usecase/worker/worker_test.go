@@ -0,0 +1,338 @@
+package worker
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+	"vk-rest/pkg/birthday"
+	"vk-rest/pkg/models"
+	"vk-rest/service/repository/profile"
+	"vk-rest/service/repository/state"
+	"vk-rest/service/repository/template"
+)
+
+// fakeProfiles records the birthday days the worker asks for by time zone.
+// Every employee is followed by subscriber.
+type fakeProfiles struct {
+	profile.IProfileRepo
+	timezones  []string
+	employees  []*models.UserItem
+	subscriber *models.UserItem
+	reminders  []int
+	asked      map[string][][]string
+}
+
+func (p *fakeProfiles) GetTimezones(ctx context.Context) ([]string, error) {
+	return p.timezones, nil
+}
+
+func (p *fakeProfiles) GetReminderDays(ctx context.Context) ([]int, error) {
+	return p.reminders, nil
+}
+
+func (p *fakeProfiles) GetBirthdayEmployees(ctx context.Context, timezone string, days []string) ([]*models.UserItem, error) {
+	p.asked[timezone] = append(p.asked[timezone], days)
+
+	employees := make([]*models.UserItem, 0)
+	for _, employee := range p.employees {
+		for _, day := range days {
+			if employee.Timezone == timezone && employee.Birthday[5:] == day {
+				employees = append(employees, employee)
+			}
+		}
+	}
+
+	return employees, nil
+}
+
+func (p *fakeProfiles) GetEmployeeByBirthday(ctx context.Context, id uint64, days int) ([]*models.UserItem, error) {
+	return []*models.UserItem{p.subscriber}, nil
+}
+
+func (p *fakeProfiles) GetChannels(ctx context.Context, id uint64) ([]*models.Channel, error) {
+	return nil, nil
+}
+
+func (p *fakeProfiles) GetDigestRecipients(ctx context.Context, timezone string, modes []string) ([]*models.DigestRecipient, error) {
+	return nil, nil
+}
+
+// fakeState keeps the last runs and the triggers in memory.
+type fakeState struct {
+	state.IStateRepo
+	runs     map[string]time.Time
+	pending  []*models.WorkerTrigger
+	finished []*models.WorkerTrigger
+}
+
+func (s *fakeState) GetLastRuns(ctx context.Context) (map[string]time.Time, error) {
+	return s.runs, nil
+}
+
+func (s *fakeState) SetLastRun(ctx context.Context, timezone string, day time.Time) error {
+	s.runs[timezone] = birthday.Date(day)
+	return nil
+}
+
+func (s *fakeState) NextFence(ctx context.Context) (int64, error) {
+	return 1, nil
+}
+
+func (s *fakeState) HasOpenTriggers(ctx context.Context) (bool, error) {
+	return len(s.pending) > 0, nil
+}
+
+func (s *fakeState) ClaimTrigger(ctx context.Context, staleBefore time.Time) (*models.WorkerTrigger, bool, error) {
+	if len(s.pending) == 0 {
+		return nil, false, nil
+	}
+
+	trigger := s.pending[0]
+	s.pending = s.pending[1:]
+	trigger.Status = models.TriggerRunning
+	return trigger, true, nil
+}
+
+func (s *fakeState) FinishTrigger(ctx context.Context, trigger *models.WorkerTrigger) error {
+	s.finished = append(s.finished, trigger)
+	return nil
+}
+
+// fakeTemplates has every template, telling belated messages apart.
+type fakeTemplates struct {
+	template.ITemplateRepo
+}
+
+func (t *fakeTemplates) FindTemplate(ctx context.Context, name, locale string) (*models.Template, bool, error) {
+	body := name + " about {{.Name}}{{if .Belated}}, belated{{end}}"
+	return &models.Template{Name: name, Locale: locale, Subject: name, Body: body}, true, nil
+}
+
+// fakeLeases gives the lease unless held is set.
+type fakeLeases struct {
+	held bool
+}
+
+func (l *fakeLeases) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return !l.held, nil
+}
+
+func (l *fakeLeases) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (l *fakeLeases) Release(ctx context.Context, name, owner string) error {
+	return nil
+}
+
+func testWorker(now time.Time, policy birthday.LeapDayPolicy, timezones ...string) (*Worker, *fakeProfiles, *fakeState) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	profiles := &fakeProfiles{timezones: timezones, asked: make(map[string][][]string)}
+	runs := &fakeState{runs: make(map[string]time.Time)}
+
+	w := &Worker{
+		log:       log,
+		now:       func() time.Time { return now },
+		leapDay:   policy,
+		profiles:  profiles,
+		state:     runs,
+		templates: &fakeTemplates{},
+		leases:    &fakeLeases{},
+		notifiers: map[string]Notifier{models.ChannelEmail: nil},
+	}
+
+	return w, profiles, runs
+}
+
+func TestHappyBirthdayLocalDay(t *testing.T) {
+	// 09:30 in Tokyo, 00:30 in UTC and 19:30 of the day before in New York
+	now := time.Date(2023, time.February, 28, 0, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		policy birthday.LeapDayPolicy
+		want   map[string][][]string
+	}{
+		{birthday.LeapDayFeb28, map[string][][]string{"Asia/Tokyo": {{"02-28", "02-29"}}, "America/New_York": {{"02-27"}}}},
+		{birthday.LeapDayMar1, map[string][][]string{"Asia/Tokyo": {{"02-28"}}, "America/New_York": {{"02-27"}}}},
+		{birthday.LeapDaySkip, map[string][][]string{"Asia/Tokyo": {{"02-28"}}, "America/New_York": {{"02-27"}}}},
+	}
+
+	for _, tt := range tests {
+		w, profiles, runs := testWorker(now, tt.policy, "Asia/Tokyo", "UTC", "America/New_York")
+		w.happyBirthday(context.Background(), false)
+
+		if !reflect.DeepEqual(profiles.asked, tt.want) {
+			t.Errorf("%s: asked for %v, want %v", tt.policy, profiles.asked, tt.want)
+		}
+
+		wantRuns := map[string]time.Time{
+			"Asia/Tokyo":       time.Date(2023, time.February, 28, 0, 0, 0, 0, time.UTC),
+			"America/New_York": time.Date(2023, time.February, 27, 0, 0, 0, 0, time.UTC),
+		}
+		if !reflect.DeepEqual(runs.runs, wantRuns) {
+			t.Errorf("%s: last runs %v, want %v", tt.policy, runs.runs, wantRuns)
+		}
+	}
+}
+
+func TestHappyBirthdayMar1(t *testing.T) {
+	// March 1 has come in UTC, but not yet in New York
+	now := time.Date(2023, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	w, profiles, _ := testWorker(now, birthday.LeapDayMar1, "UTC", "America/New_York")
+	w.happyBirthday(context.Background(), false)
+
+	want := map[string][][]string{"UTC": {{"03-01", "02-29"}}}
+	if !reflect.DeepEqual(profiles.asked, want) {
+		t.Errorf("asked for %v, want %v", profiles.asked, want)
+	}
+}
+
+func TestHappyBirthdayLeapYear(t *testing.T) {
+	now := time.Date(2024, time.February, 28, 12, 0, 0, 0, time.UTC)
+
+	w, profiles, _ := testWorker(now, birthday.LeapDayFeb28, "UTC")
+	w.happyBirthday(context.Background(), false)
+
+	want := map[string][][]string{"UTC": {{"02-28"}}}
+	if !reflect.DeepEqual(profiles.asked, want) {
+		t.Errorf("asked for %v, want %v", profiles.asked, want)
+	}
+}
+
+func TestHappyBirthdayPaused(t *testing.T) {
+	now := time.Date(2023, time.February, 28, 12, 0, 0, 0, time.UTC)
+
+	w, profiles, runs := testWorker(now, birthday.LeapDayFeb28, "UTC")
+	w.happyBirthday(context.Background(), true)
+
+	if len(profiles.asked) != 0 {
+		t.Errorf("paused worker asked for %v", profiles.asked)
+	}
+
+	if _, ok := runs.runs["UTC"]; !ok {
+		t.Error("paused day is not recorded as done")
+	}
+}
+
+func TestCatchUpDays(t *testing.T) {
+	now := time.Date(2023, time.March, 2, 12, 0, 0, 0, time.UTC)
+
+	w, profiles, runs := testWorker(now, birthday.LeapDayFeb28, "UTC")
+	w.catchUp = 3
+	runs.runs["UTC"] = time.Date(2023, time.February, 26, 0, 0, 0, 0, time.UTC)
+
+	w.happyBirthday(context.Background(), false)
+
+	// every missed day is asked twice, for the greetings and the notify
+	// messages; today only for the greetings, as no one has reminders
+	want := map[string][][]string{"UTC": {
+		{"02-27"}, {"02-27"},
+		{"02-28", "02-29"}, {"02-28", "02-29"},
+		{"03-01"}, {"03-01"},
+		{"03-02"},
+	}}
+	if !reflect.DeepEqual(profiles.asked, want) {
+		t.Errorf("asked for %v, want %v", profiles.asked, want)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	// already May 17 in Tokyo and UTC, still May 16 in Los Angeles
+	now := time.Date(2023, time.May, 17, 3, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		timezone string
+		date     time.Time
+		want     []string
+	}{
+		{"today", "UTC", time.Date(2023, time.May, 17, 0, 0, 0, 0, time.UTC), []string{"greeting about anna", "notify about anna"}},
+		{"future", "UTC", time.Date(2023, time.May, 18, 0, 0, 0, 0, time.UTC), []string{"greeting about anna", "notify about anna"}},
+		{"past", "UTC", time.Date(2023, time.May, 16, 0, 0, 0, 0, time.UTC), []string{"greeting about anna, belated", "notify about anna, belated"}},
+		{"past in Tokyo", "Asia/Tokyo", time.Date(2023, time.May, 16, 0, 0, 0, 0, time.UTC), []string{"greeting about anna, belated", "notify about anna, belated"}},
+		{"today in Los Angeles", "America/Los_Angeles", time.Date(2023, time.May, 16, 0, 0, 0, 0, time.UTC), []string{"greeting about anna", "notify about anna"}},
+	}
+
+	for _, tt := range tests {
+		w, profiles, runs := testWorker(now, birthday.LeapDayFeb28, tt.timezone)
+		profiles.reminders = []int{0}
+		profiles.subscriber = &models.UserItem{Id: 2, Login: "boris", Email: "boris@example.com", Locale: "ru"}
+		profiles.employees = []*models.UserItem{{
+			Id:       1,
+			Login:    "anna",
+			Email:    "anna@example.com",
+			Birthday: "1990" + tt.date.Format("-01-02"),
+			Timezone: tt.timezone,
+			Locale:   "ru",
+		}}
+
+		runs.pending = []*models.WorkerTrigger{{Id: 1, Date: tt.date.Format(time.DateOnly), DryRun: true}}
+		w.RunTriggers()
+
+		if len(runs.pending) != 0 || len(runs.finished) != 1 {
+			t.Fatalf("%s: %d triggers pending, %d finished, want 0 and 1", tt.name, len(runs.pending), len(runs.finished))
+		}
+
+		trigger := runs.finished[0]
+		if trigger.Status != models.TriggerDone || trigger.Error != "" {
+			t.Errorf("%s: trigger %s with error %q, want done", tt.name, trigger.Status, trigger.Error)
+		}
+
+		bodies := make([]string, 0, len(trigger.Messages))
+		for _, msg := range trigger.Messages {
+			bodies = append(bodies, msg.Body)
+			if msg.Queued {
+				t.Errorf("%s: dry run queued %s", tt.name, msg.Kind)
+			}
+		}
+
+		if !reflect.DeepEqual(bodies, tt.want) {
+			t.Errorf("%s: messages %q, want %q", tt.name, bodies, tt.want)
+		}
+	}
+}
+
+func TestRunTriggersLeaseHeld(t *testing.T) {
+	now := time.Date(2023, time.May, 17, 12, 0, 0, 0, time.UTC)
+
+	w, profiles, runs := testWorker(now, birthday.LeapDayFeb28, "UTC")
+	w.leases = &fakeLeases{held: true}
+	runs.pending = []*models.WorkerTrigger{{Id: 1, Date: "2023-05-17"}}
+
+	w.RunTriggers()
+
+	if len(runs.pending) != 1 || len(runs.finished) != 0 {
+		t.Errorf("%d triggers pending, %d finished without the lease, want 1 and 0", len(runs.pending), len(runs.finished))
+	}
+
+	if len(profiles.asked) != 0 {
+		t.Errorf("RunTriggers without the lease asked for %v", profiles.asked)
+	}
+}
+
+func TestRunTriggersBadDate(t *testing.T) {
+	now := time.Date(2023, time.May, 17, 12, 0, 0, 0, time.UTC)
+
+	w, _, runs := testWorker(now, birthday.LeapDayFeb28, "UTC")
+	runs.pending = []*models.WorkerTrigger{{Id: 1, Date: "17.05.2023"}, {Id: 2, Date: "2023-05-17"}}
+
+	w.RunTriggers()
+
+	if len(runs.finished) != 2 {
+		t.Fatalf("%d triggers finished, want 2", len(runs.finished))
+	}
+
+	if runs.finished[0].Status != models.TriggerFailed || runs.finished[0].Error == "" {
+		t.Errorf("bad date trigger %s with error %q, want failed", runs.finished[0].Status, runs.finished[0].Error)
+	}
+
+	if runs.finished[1].Status != models.TriggerDone {
+		t.Errorf("trigger after a failed one %s, want done", runs.finished[1].Status)
+	}
+}